@@ -1,9 +1,13 @@
 package minieng
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 )
 
 // FileLoader implements support for loading and releasing file resources.
@@ -18,12 +22,37 @@ type FileLoader interface {
 	Resource(url string) (Resource, error)
 }
 
+// Decoder is an optional interface a FileLoader can implement to decode its files away from the main thread, when
+// they are loaded from another goroutine (i.e. by the Preload of a Scene given to TransitionTo). The FileLoaders
+// which don't implement it have Load called on the main thread.
+type Decoder interface {
+	// Decode decodes the given resource, without touching the GPU. It may be called from any goroutine.
+	Decode(url string, data io.Reader) (interface{}, error)
+
+	// Upload finishes loading the given resource from what Decode returned, i.e. by sending it to the GPU. It is
+	// called on the main thread.
+	Upload(url string, decoded interface{}) error
+}
+
 // Resource represents a game resource, such as an image or a sound.
 type Resource interface {
 	// URL returns the uniform resource locator of the given resource.
 	URL() string
 }
 
+var (
+	// owner is the Scene being preloaded or set up on the main thread, which owns the resources loaded through
+	// Files meanwhile. The files loaded by a Preload called by TransitionTo are attributed to its Scene separately.
+	owner     *sceneWrapper
+	ownerLock sync.Mutex
+
+	// owners counts the Scenes owning each url, plus one for the urls loaded without an owner
+	owners = make(map[string]int)
+
+	// unowned are the urls loaded without an owner, they are only unloaded by Files.Unload
+	unowned = make(map[string]bool)
+)
+
 // Files manages global resource handling of registered file formats for game
 // assets.
 var Files = &Formats{formats: make(map[string]FileLoader)}
@@ -55,6 +84,10 @@ func (formats *Formats) Register(ext string, loader FileLoader) {
 
 // load loads the given resource into memory.
 func (formats *Formats) load(url string) error {
+	if !onMainThread() {
+		return formats.loadInBackground(url)
+	}
+
 	ext := filepath.Ext(url)
 	if loader, ok := Files.formats[ext]; ok {
 		f, err := openFile(filepath.Join(formats.root, url))
@@ -63,24 +96,113 @@ func (formats *Formats) load(url string) error {
 		}
 		defer f.Close()
 
-		return loader.Load(url, f)
+		if err = loader.Load(url, f); err == nil {
+			formats.own(url)
+		}
+		return err
 	}
 	return fmt.Errorf("no `FileLoader` associated with this extension: %q in url %q", ext, url)
 }
 
-// Load loads the given resource(s) into memory, stopping at the first error.
-func (formats *Formats) Load(urls ...string) error {
-	for _, url := range urls {
-		err := formats.load(url)
+// read reads the given resource, without loading it. It is safe to call from any goroutine, as long as no
+// FileLoader is being registered.
+func (formats *Formats) read(url string) (FileLoader, []byte, error) {
+	ext := filepath.Ext(url)
+	loader, ok := formats.formats[ext]
+	if !ok {
+		return nil, nil, fmt.Errorf("no `FileLoader` associated with this extension: %q in url %q", ext, url)
+	}
+
+	f, err := openFile(filepath.Join(formats.root, url))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to open resource: %s", err)
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read resource: %s", err)
+	}
+	return loader, data, nil
+}
+
+// loadInBackground reads and decodes the given resource on the calling goroutine, and has it uploaded on the main
+// thread, waiting for it. The resource is owned by the Scene whose Preload is running on the calling goroutine, if
+// any, see hold otherwise.
+func (formats *Formats) loadInBackground(url string) error {
+	loader, data, err := formats.read(url)
+	if err != nil {
+		return err
+	}
+
+	upload := func() error { return loader.Load(url, bytes.NewReader(data)) }
+	if decoder, ok := loader.(Decoder); ok {
+		decoded, err := decoder.Decode(url, bytes.NewReader(data))
 		if err != nil {
 			return err
 		}
+		upload = func() error { return decoder.Upload(url, decoded) }
+	}
+
+	p := preloadOn(goroutineID())
+	done := make(chan error, 1)
+	postMain(func() {
+		err := upload()
+		if err == nil && formats == Files {
+			if p != nil {
+				p.own(url)
+			} else {
+				ownerLock.Lock()
+				hold(url)
+				ownerLock.Unlock()
+			}
+		}
+		done <- err
+	})
+	return <-done
+}
+
+// Load loads the given resource(s) into memory, stopping at the first error. It can be called from any goroutine:
+// away from the main thread, the files are read and decoded (see Decoder) on the calling goroutine, which then waits
+// for the main loop to upload them.
+func (formats *Formats) Load(urls ...string) error {
+	// the urls make up the LoadingProgress when given by the Preload of a transition
+	var p *preload
+	if !onMainThread() {
+		p = preloadOn(goroutineID())
+	}
+	if p != nil {
+		atomic.AddInt32(&p.total, int32(len(urls)))
+	}
+
+	for i, url := range urls {
+		if err := formats.load(url); err != nil {
+			if p != nil {
+				atomic.AddInt32(&p.loaded, int32(len(urls)-i))
+			}
+			return err
+		}
+		if p != nil {
+			atomic.AddInt32(&p.loaded, 1)
+		}
 	}
 	return nil
 }
 
 // Unload releases the given resource from memory.
 func (formats *Formats) Unload(url string) error {
+	if formats == Files {
+		// the Scenes owning it keep counting, only the hold of the load without an owner is given up
+		ownerLock.Lock()
+		if unowned[url] {
+			delete(unowned, url)
+			if owners[url]--; owners[url] == 0 {
+				delete(owners, url)
+			}
+		}
+		ownerLock.Unlock()
+	}
+
 	ext := filepath.Ext(url)
 	if loader, ok := Files.formats[ext]; ok {
 		return loader.Unload(url)
@@ -96,3 +218,74 @@ func (formats *Formats) Resource(url string) (Resource, error) {
 	}
 	return nil, fmt.Errorf("no `FileLoader` associated with this extension: %q in url %q", ext, url)
 }
+
+// own records the url as loaded by the Scene currently preloaded or set up, if any, so it is unloaded along with it.
+func (formats *Formats) own(url string) {
+	if formats != Files {
+		return
+	}
+
+	ownerLock.Lock()
+	defer ownerLock.Unlock()
+
+	if owner == nil {
+		hold(url)
+		return
+	}
+	if owner.urls[url] {
+		return
+	}
+	if owner.urls == nil {
+		owner.urls = make(map[string]bool)
+	}
+	owner.urls[url] = true
+	owners[url]++
+}
+
+// hold records the url as loaded without an owner, so it stays loaded when the Scenes loading it as well are
+// discarded. ownerLock must be held.
+func hold(url string) {
+	if !unowned[url] {
+		unowned[url] = true
+		owners[url]++
+	}
+}
+
+// setOwner sets the Scene which owns the resources loaded from now on, nil for none. It returns the previous one.
+func setOwner(wrapper *sceneWrapper) *sceneWrapper {
+	ownerLock.Lock()
+	previous := owner
+	owner = wrapper
+	ownerLock.Unlock()
+	return previous
+}
+
+// release unloads the resources owned by the Scene, unless another Scene owns them as well. It returns the first
+// error encountered, but unloads as much as it can.
+func release(wrapper *sceneWrapper) error {
+	err := releaseURLs(wrapper.urls)
+	wrapper.urls = nil
+	return err
+}
+
+// releaseURLs gives up one ownership of each url, and unloads the ones nobody owns anymore.
+func releaseURLs(urls map[string]bool) error {
+	ownerLock.Lock()
+	var unused []string
+	for url := range urls {
+		owners[url]--
+		if owners[url] == 0 {
+			delete(owners, url)
+			unused = append(unused, url)
+		}
+	}
+	ownerLock.Unlock()
+
+	var err error
+	for _, url := range unused {
+		if unloadErr := Files.Unload(url); unloadErr != nil && err == nil {
+			err = unloadErr
+		}
+	}
+	return err
+}
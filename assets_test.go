@@ -0,0 +1,67 @@
+//+build headless
+
+package minieng
+
+import "testing"
+
+// loadTestScene loads its url during Preload
+type loadTestScene struct {
+	testScene
+	url string
+}
+
+func (s *loadTestScene) Preload() { Files.Load(s.url) }
+
+// resetOwners forgets about the urls loaded by a test
+func resetOwners() {
+	owners = make(map[string]int)
+	unowned = make(map[string]bool)
+}
+
+func TestUnloadWithScene(t *testing.T) {
+	defer resetScenes()
+	defer resetOwners()
+	loader := &decodeTestLoader{loaded: make(map[string]bool)}
+	defer withTestFiles(t, loader, "a.tst")()
+
+	SetScene(&loadTestScene{testScene: testScene{name: "loading a"}, url: "a.tst"}, false)
+	SetScene(&testScene{name: "other"}, false)
+	if !loader.loaded["a.tst"] {
+		t.Fatal("the file was not loaded")
+	}
+
+	if err := UnregisterScene("loading a"); err != nil {
+		t.Fatalf("UnregisterScene: %v", err)
+	}
+	if loader.loaded["a.tst"] {
+		t.Error("the file is still loaded once its Scene got discarded")
+	}
+}
+
+func TestUnloadWithoutOwner(t *testing.T) {
+	defer resetScenes()
+	defer resetOwners()
+	loader := &decodeTestLoader{loaded: make(map[string]bool)}
+	defer withTestFiles(t, loader, "a.tst")()
+
+	// loaded outside of any Scene, then by a Scene
+	if err := Files.Load("a.tst"); err != nil {
+		t.Fatalf("Files.Load: %v", err)
+	}
+	SetScene(&loadTestScene{testScene: testScene{name: "loading a"}, url: "a.tst"}, false)
+	SetScene(&testScene{name: "other"}, false)
+
+	if err := UnregisterScene("loading a"); err != nil {
+		t.Fatalf("UnregisterScene: %v", err)
+	}
+	if !loader.loaded["a.tst"] {
+		t.Fatal("the file loaded without an owner got unloaded along with the Scene")
+	}
+
+	if err := Files.Unload("a.tst"); err != nil {
+		t.Fatalf("Files.Unload: %v", err)
+	}
+	if loader.loaded["a.tst"] || owners["a.tst"] != 0 {
+		t.Errorf("the file is still loaded after Files.Unload, owned %d times", owners["a.tst"])
+	}
+}
@@ -0,0 +1,366 @@
+package minieng
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BindingsFormat is a file format for the InputBindings.
+type BindingsFormat int
+
+const (
+	// BindingsJSON saves the InputBindings as JSON
+	BindingsJSON BindingsFormat = iota
+	// BindingsYAML saves the InputBindings as YAML
+	BindingsYAML
+)
+
+// InputBindings is the serializable form of the Buttons and Axes registered on an InputManager. Keys are stored by
+// name, see Key.String, the Chords and Sequences of the Buttons as in Chord.String and Sequence.String:
+//
+//    buttons:
+//      jump: [Space, Gamepad0.A]
+//      save: [Control+S]
+//      top: [G G]
+//    axes:
+//      horizontal:
+//      - {min: ArrowLeft, max: ArrowRight}
+//      - {gamepad: 0, axis: LeftX, deadzone: 0.2}
+type InputBindings struct {
+	Buttons map[string][]string      `json:"buttons,omitempty" yaml:"buttons,omitempty"`
+	Axes    map[string][]AxisBinding `json:"axes,omitempty" yaml:"axes,omitempty"`
+}
+
+// AxisBinding is the serializable form of an AxisPair: an AxisKeyPair when Min and Max are set, an AxisMouse when
+// Mouse is set, and an AxisGamepad when Axis is set.
+type AxisBinding struct {
+	Min string `json:"min,omitempty" yaml:"min,omitempty"`
+	Max string `json:"max,omitempty" yaml:"max,omitempty"`
+
+	// Mouse is either "horizontal" or "vertical"
+	Mouse string `json:"mouse,omitempty" yaml:"mouse,omitempty"`
+
+	Gamepad  int     `json:"gamepad,omitempty" yaml:"gamepad,omitempty"`
+	Axis     string  `json:"axis,omitempty" yaml:"axis,omitempty"`
+	Deadzone float32 `json:"deadzone,omitempty" yaml:"deadzone,omitempty"`
+	Invert   bool    `json:"invert,omitempty" yaml:"invert,omitempty"`
+}
+
+// InputConflict is a key triggering several actions, see InputManager.Conflicts.
+type InputConflict struct {
+	Key     Key
+	Actions []string
+}
+
+// Bindings returns the Buttons and Axes registered on the InputManager. AxisPairs which are not provided by minieng
+// are left out.
+func (im *InputManager) Bindings() InputBindings {
+	b := InputBindings{
+		Buttons: make(map[string][]string),
+		Axes:    make(map[string][]AxisBinding),
+	}
+
+	for name, button := range im.buttons {
+		keys := make([]string, 0, len(button.Triggers))
+		for _, k := range button.Triggers {
+			keys = append(keys, k.String())
+		}
+		for _, chord := range button.Chords {
+			keys = append(keys, chord.String())
+		}
+		for _, seq := range button.Sequences {
+			keys = append(keys, seq.String())
+		}
+		b.Buttons[name] = keys
+	}
+
+	for name, axis := range im.axes {
+		var pairs []AxisBinding
+		for _, pair := range axis.Pairs {
+			switch p := pair.(type) {
+			case AxisKeyPair:
+				pairs = append(pairs, AxisBinding{Min: p.Min.String(), Max: p.Max.String()})
+			case *AxisMouse:
+				direction := "vertical"
+				if p.direction == AxisMouseHori {
+					direction = "horizontal"
+				}
+				pairs = append(pairs, AxisBinding{Mouse: direction})
+			case AxisGamepad:
+				if p.Axis < 0 || p.Axis >= gamepadAxisCount {
+					// it couldn't be loaded back
+					continue
+				}
+				pairs = append(pairs, AxisBinding{
+					Gamepad:  p.ID,
+					Axis:     p.Axis.String(),
+					Deadzone: p.Deadzone,
+					Invert:   p.Invert,
+				})
+			}
+		}
+		b.Axes[name] = pairs
+	}
+	return b
+}
+
+// SetBindings registers the Buttons and Axes of the given InputBindings, replacing the ones with the same name.
+// Nothing is registered if one of the keys is unknown. The Sequences get the SequenceTimeout.
+func (im *InputManager) SetBindings(b InputBindings) error {
+	buttons := make(map[string]Button)
+	for name, triggers := range b.Buttons {
+		button := Button{Name: name}
+		for _, trigger := range triggers {
+			seq, err := parseSequence(trigger)
+			if err != nil {
+				return fmt.Errorf("button %s: %v", name, err)
+			}
+			switch step := seq.Steps[0]; {
+			case len(seq.Steps) > 1:
+				button.Sequences = append(button.Sequences, seq)
+			case step.Modifiers == 0 && len(step.Keys) == 1:
+				button.Triggers = append(button.Triggers, step.Keys[0])
+			default:
+				button.Chords = append(button.Chords, step)
+			}
+		}
+		buttons[name] = button
+	}
+
+	axes := make(map[string][]AxisPair)
+	for name, bindings := range b.Axes {
+		var pairs []AxisPair
+		for _, binding := range bindings {
+			pair, err := binding.pair()
+			if err != nil {
+				return fmt.Errorf("axis %s: %v", name, err)
+			}
+			pairs = append(pairs, pair)
+		}
+		axes[name] = pairs
+	}
+
+	for name, button := range buttons {
+		im.buttons[name] = button
+	}
+	for name, pairs := range axes {
+		im.RegisterAxis(name, pairs...)
+	}
+	return nil
+}
+
+// pair returns the AxisPair described by the binding.
+func (b AxisBinding) pair() (AxisPair, error) {
+	switch {
+	case b.Mouse == "horizontal":
+		return NewAxisMouse(AxisMouseHori), nil
+	case b.Mouse == "vertical":
+		return NewAxisMouse(AxisMouseVert), nil
+	case b.Mouse != "":
+		return nil, fmt.Errorf("unknown mouse direction %q", b.Mouse)
+
+	case b.Axis != "":
+		for axis := GamepadAxis(0); axis < gamepadAxisCount; axis++ {
+			if axis.String() == b.Axis {
+				return AxisGamepad{ID: b.Gamepad, Axis: axis, Deadzone: b.Deadzone, Invert: b.Invert}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown gamepad axis %q", b.Axis)
+	}
+
+	min, err := ParseKey(b.Min)
+	if err != nil {
+		return nil, err
+	}
+	max, err := ParseKey(b.Max)
+	if err != nil {
+		return nil, err
+	}
+	return AxisKeyPair{Min: min, Max: max}, nil
+}
+
+// parseSequence parses a Sequence written as in Sequence.String. A single key is a Sequence of a single step.
+func parseSequence(s string) (Sequence, error) {
+	var seq Sequence
+	for _, step := range strings.Fields(s) {
+		var chord Chord
+		for _, name := range strings.Split(step, "+") {
+			if mod := parseModifier(name); mod != 0 {
+				chord.Modifiers |= mod
+				continue
+			}
+			k, err := ParseKey(name)
+			if err != nil {
+				return seq, err
+			}
+			chord.Keys = append(chord.Keys, k)
+		}
+		if len(chord.Keys) == 0 {
+			return seq, fmt.Errorf("no key in %q", step)
+		}
+		seq.Steps = append(seq.Steps, chord)
+	}
+	if len(seq.Steps) == 0 {
+		return seq, fmt.Errorf("empty trigger")
+	}
+	return seq, nil
+}
+
+// parseModifier returns the modifier with the given name, see Modifier.String, or 0.
+func parseModifier(name string) Modifier {
+	for i, modifierName := range modifierNames {
+		if modifierName == name {
+			return Modifier(1 << uint(i))
+		}
+	}
+	return 0
+}
+
+// LoadBindings reads InputBindings in the given format, and registers them with SetBindings.
+func (im *InputManager) LoadBindings(r io.Reader, format BindingsFormat) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var b InputBindings
+	if format == BindingsYAML {
+		err = yaml.Unmarshal(data, &b)
+	} else {
+		err = json.Unmarshal(data, &b)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse the input bindings: %v", err)
+	}
+	return im.SetBindings(b)
+}
+
+// SaveBindings writes the InputBindings of the InputManager in the given format.
+func (im *InputManager) SaveBindings(w io.Writer, format BindingsFormat) error {
+	var data []byte
+	var err error
+	if format == BindingsYAML {
+		data, err = yaml.Marshal(im.Bindings())
+	} else {
+		data, err = json.MarshalIndent(im.Bindings(), "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// ListenNextKey calls fn with the next key or gamepad button pressed, instead of letting the Buttons see it, nor its
+// release. It is meant for the options menus:
+//
+//    minieng.Input.ListenNextKey(func(k minieng.Key) {
+//        if k != minieng.Escape {
+//            minieng.Input.RebindButton("jump", 0, k)
+//        }
+//    })
+//
+// Calling it again replaces fn, a nil fn stops listening.
+func (im *InputManager) ListenNextKey(fn func(k Key)) {
+	im.keys.setListen(fn)
+}
+
+// RebindButton replaces the trigger at index i of the named Button with the given key, or adds the key when i is
+// out of range. The Chords and Sequences of the Button are kept. It returns the other actions also triggered by that
+// key, see ActionsUsing.
+func (im *InputManager) RebindButton(name string, i int, k Key) []string {
+	button := im.buttons[name]
+	button.Name = name
+	button.Triggers = append([]Key(nil), button.Triggers...)
+	if i >= 0 && i < len(button.Triggers) {
+		button.Triggers[i] = k
+	} else {
+		button.Triggers = append(button.Triggers, k)
+	}
+	im.buttons[name] = button
+
+	var others []string
+	for _, action := range im.ActionsUsing(k) {
+		if action != name {
+			others = append(others, action)
+		}
+	}
+	return others
+}
+
+// ActionsUsing returns the names of the Buttons and Axes triggered by the given key, alone or within a Chord or a
+// Sequence, sorted.
+func (im *InputManager) ActionsUsing(k Key) []string {
+	var actions []string
+	for name, button := range im.buttons {
+		for _, trigger := range button.keys() {
+			if trigger == k {
+				actions = append(actions, name)
+				break
+			}
+		}
+	}
+	for name, axis := range im.axes {
+		for _, pair := range axis.Pairs {
+			if keys, ok := pair.(AxisKeyPair); ok && (keys.Min == k || keys.Max == k) {
+				actions = append(actions, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(actions)
+	return actions
+}
+
+// Conflicts returns the keys used by more than one Button or Axis, see ActionsUsing, sorted by name. Some conflicts
+// may be intended, i.e. Enter both confirming a dialog and opening the chat.
+func (im *InputManager) Conflicts() []InputConflict {
+	seen := make(map[Key]bool)
+	for _, button := range im.buttons {
+		for _, k := range button.keys() {
+			seen[k] = true
+		}
+	}
+	for _, axis := range im.axes {
+		for _, pair := range axis.Pairs {
+			if keys, ok := pair.(AxisKeyPair); ok {
+				seen[keys.Min] = true
+				seen[keys.Max] = true
+			}
+		}
+	}
+
+	var conflicts []InputConflict
+	for k := range seen {
+		if actions := im.ActionsUsing(k); len(actions) > 1 {
+			conflicts = append(conflicts, InputConflict{Key: k, Actions: actions})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Key.String() < conflicts[j].Key.String()
+	})
+	return conflicts
+}
+
+// keys returns the keys used by the Button, by its Triggers, Chords and Sequences. The modifiers of the Chords are
+// left out.
+func (b Button) keys() []Key {
+	keys := append([]Key(nil), b.Triggers...)
+	for _, chord := range b.Chords {
+		keys = append(keys, chord.Keys...)
+	}
+	for _, seq := range b.Sequences {
+		for _, step := range seq.Steps {
+			keys = append(keys, step.Keys...)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,129 @@
+//+build headless
+
+package minieng
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBindings = `
+buttons:
+  jump: [Space, Gamepad0.A]
+  fire: [Enter]
+axes:
+  horizontal:
+  - {min: ArrowLeft, max: ArrowRight}
+  - {mouse: horizontal}
+  - {gamepad: 1, axis: LeftX, deadzone: 0.2, invert: true}
+`
+
+func TestLoadAndSaveBindings(t *testing.T) {
+	defer func(input *InputManager) { Input = input }(Input)
+	// NewAxisMouse reads the Mouse of the engine
+	Input = NewInputManager()
+	im := NewInputManager()
+	if err := im.LoadBindings(strings.NewReader(testBindings), BindingsYAML); err != nil {
+		t.Fatalf("LoadBindings: %v", err)
+	}
+
+	b := im.Bindings()
+	if jump := b.Buttons["jump"]; !reflect.DeepEqual(jump, []string{"Space", "Gamepad0.A"}) {
+		t.Errorf("jump bound to %v, want Space and Gamepad0.A", jump)
+	}
+	want := []AxisBinding{
+		{Min: "ArrowLeft", Max: "ArrowRight"},
+		{Mouse: "horizontal"},
+		{Gamepad: 1, Axis: "LeftX", Deadzone: 0.2, Invert: true},
+	}
+	if horizontal := b.Axes["horizontal"]; !reflect.DeepEqual(horizontal, want) {
+		t.Errorf("horizontal bound to %+v, want %+v", horizontal, want)
+	}
+
+	// JSON round trip
+	var saved bytes.Buffer
+	if err := im.SaveBindings(&saved, BindingsJSON); err != nil {
+		t.Fatalf("SaveBindings: %v", err)
+	}
+	loaded := NewInputManager()
+	if err := loaded.LoadBindings(&saved, BindingsJSON); err != nil {
+		t.Fatalf("LoadBindings: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Bindings(), b) {
+		t.Errorf("bindings after a round trip: %+v, want %+v", loaded.Bindings(), b)
+	}
+}
+
+func TestSetBindingsUnknownKey(t *testing.T) {
+	im := NewInputManager()
+	err := im.SetBindings(InputBindings{Buttons: map[string][]string{
+		"jump": {"Space"},
+		"fire": {"NoSuchKey"},
+	}})
+	if err == nil {
+		t.Fatal("SetBindings succeeded with an unknown key")
+	}
+	if len(im.Bindings().Buttons) != 0 {
+		t.Errorf("buttons registered despite the error: %v", im.Bindings().Buttons)
+	}
+	if err := im.SetBindings(InputBindings{Axes: map[string][]AxisBinding{"x": {{Axis: "NoSuchAxis"}}}}); err == nil {
+		t.Error("SetBindings succeeded with an unknown gamepad axis")
+	}
+}
+
+func TestListenNextKey(t *testing.T) {
+	defer func(input *InputManager) { Input = input }(Input)
+	Input = NewInputManager()
+	Input.RegisterButton("jump", Space)
+
+	var heard []Key
+	Input.ListenNextKey(func(k Key) { heard = append(heard, k) })
+
+	// the keys set during a frame are seen by the Systems until update ends it
+	Input.keys.Set(Space, true)
+	if len(heard) != 1 || heard[0] != Space {
+		t.Errorf("heard %v, want Space", heard)
+	}
+	if Input.Button("jump").JustPressed() {
+		t.Error("the key given to ListenNextKey pressed the Button")
+	}
+	Input.update()
+
+	// its release is swallowed, the next press is not
+	Input.keys.Set(Space, false)
+	if Input.Button("jump").JustReleased() {
+		t.Error("the release of the key given to ListenNextKey got through")
+	}
+	Input.update()
+	Input.keys.Set(Space, true)
+	if !Input.Button("jump").JustPressed() || len(heard) != 1 {
+		t.Errorf("the next press was not seen by the Button, heard %v", heard)
+	}
+}
+
+func TestRebindButton(t *testing.T) {
+	im := NewInputManager()
+	im.RegisterButton("jump", Space)
+	im.RegisterButton("fire", Enter)
+	im.RegisterAxis("horizontal", AxisKeyPair{Min: ArrowLeft, Max: ArrowRight})
+
+	if others := im.RebindButton("jump", 0, Enter); !reflect.DeepEqual(others, []string{"fire"}) {
+		t.Errorf("RebindButton returned %v, want [fire]", others)
+	}
+	if others := im.RebindButton("fire", 1, ArrowLeft); !reflect.DeepEqual(others, []string{"horizontal"}) {
+		t.Errorf("RebindButton returned %v, want [horizontal]", others)
+	}
+	if triggers := im.Button("fire").Triggers; !reflect.DeepEqual(triggers, []Key{Enter, ArrowLeft}) {
+		t.Errorf("fire triggered by %v, want Enter and ArrowLeft", triggers)
+	}
+
+	want := []InputConflict{
+		{Key: ArrowLeft, Actions: []string{"fire", "horizontal"}},
+		{Key: Enter, Actions: []string{"fire", "jump"}},
+	}
+	if conflicts := im.Conflicts(); !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("Conflicts() = %v, want %v", conflicts, want)
+	}
+}
@@ -1,9 +1,49 @@
 package minieng
 
+import "strings"
+
+// SequenceTimeout is the number of seconds a Sequence waits for its next step, when its Timeout is 0
+var SequenceTimeout float32 = 0.5
+
 // A Button is an input which can be either JustPressed, JustReleased or Down. Common uses would be for, a jump key or an action key.
+// Any of its Triggers, Chords or Sequences sets it off.
 type Button struct {
-	Triggers []Key
-	Name     string
+	Triggers  []Key
+	Chords    []Chord
+	Sequences []Sequence
+	Name      string
+}
+
+// Chord is a Button trigger made of keys held together, along with exactly the given modifiers, e.g. Ctrl+S. It is
+// pressed by the last of its keys going down.
+type Chord struct {
+	Modifiers Modifier
+	Keys      []Key
+}
+
+// Sequence is a Button trigger made of Chords pressed one after the other, each one within Timeout seconds of the
+// previous one, e.g. a double tap or "g g". Pressing another key starts it over. It is pressed by its last step, and
+// stays down as long as that step is held.
+type Sequence struct {
+	Steps   []Chord
+	Timeout float32
+}
+
+// sequenceState is the progress of a Sequence, updated once every frame
+type sequenceState struct {
+	// step is the next step expected, pressed at the time last
+	step int
+	last float32
+
+	pressed, down, released bool
+}
+
+// modifierKeys are the keys behind the modifiers
+var modifierKeys = map[Key]Modifier{
+	LeftShift: Shift, RightShift: Shift,
+	LeftControl: Control, RightControl: Control,
+	LeftAlt: Alt, RightAlt: Alt,
+	LeftSuper: Super, RightSuper: Super,
 }
 
 // JustPressed checks whether an input was pressed in the previous frame.
@@ -14,6 +54,16 @@ func (b Button) JustPressed() bool {
 			return v
 		}
 	}
+	for _, chord := range b.Chords {
+		if chord.JustPressed() {
+			return true
+		}
+	}
+	for _, state := range b.sequences() {
+		if state.pressed {
+			return true
+		}
+	}
 
 	return false
 }
@@ -26,6 +76,16 @@ func (b Button) JustReleased() bool {
 			return v
 		}
 	}
+	for _, chord := range b.Chords {
+		if chord.JustReleased() {
+			return true
+		}
+	}
+	for _, state := range b.sequences() {
+		if state.released {
+			return true
+		}
+	}
 
 	return false
 }
@@ -38,6 +98,152 @@ func (b Button) Down() bool {
 			return v
 		}
 	}
+	for _, chord := range b.Chords {
+		if chord.Down() {
+			return true
+		}
+	}
+	for _, state := range b.sequences() {
+		if state.down && !state.pressed {
+			return true
+		}
+	}
 
 	return false
 }
+
+// sequences returns the progress of the Sequences of the Button, nothing while the input is blocked.
+func (b Button) sequences() []sequenceState {
+	if Input.blocked {
+		return nil
+	}
+	return Input.sequences[b.Name]
+}
+
+// JustPressed returns whether the chord was just completed.
+func (c Chord) JustPressed() bool {
+	if !c.held(false) {
+		return false
+	}
+	for _, k := range c.Keys {
+		if Input.keys.Get(k).JustPressed() {
+			return true
+		}
+	}
+	return false
+}
+
+// JustReleased returns whether the chord was just broken, by releasing a key or changing the modifiers.
+func (c Chord) JustReleased() bool {
+	return c.held(true) && !c.held(false)
+}
+
+// Down returns whether the chord is being held.
+func (c Chord) Down() bool {
+	return c.held(true) && c.held(false)
+}
+
+// held returns whether the keys of the chord, and only its modifiers, are held now or during the previous frame.
+func (c Chord) held(previous bool) bool {
+	if len(c.Keys) == 0 {
+		return false
+	}
+
+	mods := c.Modifiers
+	for _, k := range c.Keys {
+		ks := Input.keys.Get(k)
+		if previous && !ks.lastState || !previous && !ks.currentState {
+			return false
+		}
+		mods |= modifierKeys[k]
+	}
+	return Input.keys.modifiers(previous) == mods
+}
+
+// String returns the chord as written in the InputBindings, i.e. "Control+S".
+func (c Chord) String() string {
+	var parts []string
+	if c.Modifiers != 0 {
+		parts = append(parts, c.Modifiers.String())
+	}
+	for _, k := range c.Keys {
+		parts = append(parts, k.String())
+	}
+	return strings.Join(parts, "+")
+}
+
+// String returns the sequence as written in the InputBindings, i.e. "G G".
+func (s Sequence) String() string {
+	steps := make([]string, len(s.Steps))
+	for i, step := range s.Steps {
+		steps[i] = step.String()
+	}
+	return strings.Join(steps, " ")
+}
+
+// updateSequences moves the Sequences of the Buttons along with the keys pressed during the current frame. It is
+// invoked once every frame.
+func (im *InputManager) updateSequences() {
+	pressed := im.keys.justPressed()
+	others := false
+	for _, k := range pressed {
+		if modifierKeys[k] == 0 {
+			others = true
+		}
+	}
+	now := clockTime()
+
+	for name, button := range im.buttons {
+		if len(button.Sequences) == 0 {
+			delete(im.sequences, name)
+			continue
+		}
+		states := im.sequences[name]
+		if len(states) != len(button.Sequences) {
+			states = make([]sequenceState, len(button.Sequences))
+			if im.sequences == nil {
+				im.sequences = make(map[string][]sequenceState)
+			}
+			im.sequences[name] = states
+		}
+
+		for i, seq := range button.Sequences {
+			st := &states[i]
+			if len(seq.Steps) == 0 {
+				continue
+			}
+			st.pressed, st.released = false, false
+			if st.down && !seq.Steps[len(seq.Steps)-1].held(false) {
+				st.down, st.released = false, true
+			}
+
+			timeout := seq.Timeout
+			if timeout == 0 {
+				timeout = SequenceTimeout
+			}
+			if st.step > 0 && now-st.last > timeout {
+				st.step = 0
+			}
+			if len(pressed) == 0 {
+				continue
+			}
+
+			switch {
+			case seq.Steps[st.step].JustPressed():
+				st.step++
+			case st.step > 0 && seq.Steps[0].JustPressed():
+				st.step = 1
+			case others:
+				st.step = 0
+				continue
+			default:
+				continue
+			}
+			st.last = now
+			if st.step == len(seq.Steps) {
+				st.step = 0
+				st.pressed, st.down = true, true
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+//+build headless
+
+package minieng
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// buttonFrame sets the keys, and runs a frame after the given delay, returning whether the Button was just pressed
+func buttonFrame(name string, delay time.Duration, keys map[Key]bool) bool {
+	for k, down := range keys {
+		Input.keys.Set(k, down)
+	}
+	Time.Advance(delay)
+	Time.Tick()
+	Input.updateSequences()
+	pressed := Input.Button(name).JustPressed()
+	Input.update()
+	return pressed
+}
+
+func TestChord(t *testing.T) {
+	defer func(input *InputManager, clock *Clock) { Input, Time = input, clock }(Input, Time)
+	Input, Time = NewInputManager(), NewManualClock()
+	Input.RegisterChord("save", Control, S)
+
+	if buttonFrame("save", 0, map[Key]bool{S: true}) {
+		t.Error("S alone pressed Control+S")
+	}
+	buttonFrame("save", 0, map[Key]bool{S: false})
+
+	buttonFrame("save", 0, map[Key]bool{LeftControl: true})
+	if !buttonFrame("save", 0, map[Key]bool{S: true}) {
+		t.Error("Control then S did not press Control+S")
+	}
+	if !Input.Button("save").Down() {
+		t.Error("Control+S is not down while held")
+	}
+	buttonFrame("save", 0, map[Key]bool{S: false})
+
+	// the modifiers have to match exactly
+	buttonFrame("save", 0, map[Key]bool{LeftShift: true})
+	if buttonFrame("save", 0, map[Key]bool{S: true}) {
+		t.Error("Control+Shift+S pressed Control+S")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	defer func(input *InputManager, clock *Clock) { Input, Time = input, clock }(Input, Time)
+	Input, Time = NewInputManager(), NewManualClock()
+	g := Chord{Keys: []Key{G}}
+	Input.RegisterSequence("top", 0.5, g, g)
+
+	tap := func(k Key, delay time.Duration) bool {
+		pressed := buttonFrame("top", delay, map[Key]bool{k: true})
+		buttonFrame("top", 0, map[Key]bool{k: false})
+		return pressed
+	}
+
+	if tap(G, 0) {
+		t.Error("the first step pressed the Sequence")
+	}
+	if !tap(G, 100*time.Millisecond) {
+		t.Error("G G did not press the Sequence")
+	}
+
+	tap(G, time.Second)
+	if tap(G, time.Second) {
+		t.Error("the Sequence was pressed after its timeout")
+	}
+
+	tap(G, time.Second)
+	tap(A, 100*time.Millisecond)
+	if tap(G, 100*time.Millisecond) {
+		t.Error("the Sequence was pressed despite another key in between")
+	}
+}
+
+func TestChordAndSequenceBindings(t *testing.T) {
+	im := NewInputManager()
+	err := im.SetBindings(InputBindings{Buttons: map[string][]string{
+		"save": {"Control+S"},
+		"top":  {"G G"},
+		"grab": {"G"},
+	}})
+	if err != nil {
+		t.Fatalf("SetBindings: %v", err)
+	}
+
+	save := im.Button("save")
+	if want := []Chord{{Modifiers: Control, Keys: []Key{S}}}; !reflect.DeepEqual(save.Chords, want) {
+		t.Errorf("save chords = %v, want %v", save.Chords, want)
+	}
+	if got := im.Bindings().Buttons["top"]; !reflect.DeepEqual(got, []string{"G G"}) {
+		t.Errorf("top bound to %v, want [G G]", got)
+	}
+
+	if actions := im.ActionsUsing(G); !reflect.DeepEqual(actions, []string{"grab", "top"}) {
+		t.Errorf("ActionsUsing(G) = %v, want [grab top]", actions)
+	}
+	if actions := im.ActionsUsing(S); !reflect.DeepEqual(actions, []string{"save"}) {
+		t.Errorf("ActionsUsing(S) = %v, want [save]", actions)
+	}
+}
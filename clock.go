@@ -1,6 +1,7 @@
 package minieng
 
 import (
+	"sync/atomic"
 	"time"
 )
 
@@ -11,11 +12,16 @@ const secondsInNano int64 = 1000000000
 type Clock struct {
 	counter   uint32
 	perSecond uint32
+	frames    uint64
 
 	deltaStamp int64
 	elapsStamp int64
 	frameStamp int64
 	startStamp int64
+
+	// manual is true whenever the clock only advances through `Advance`
+	manual      bool
+	manualStamp int64
 }
 
 // NewClock creates a new timer which allows you to measure ticks per seconds. Be sure to call `Tick()` whenever you
@@ -29,11 +35,39 @@ func NewClock() *Clock {
 	return clock
 }
 
+// NewManualClock creates a new timer which does not follow the wall clock. Time only passes when `Advance` is
+// called, which makes it possible to drive the engine deterministically (i.e. in headless mode).
+func NewManualClock() *Clock {
+	clock := new(Clock)
+	clock.manual = true
+	return clock
+}
+
+// Advance moves a manual clock forward by the given duration. The next call to `Tick()` will report it as `Delta()`.
+// It has no effect on a clock created with `NewClock`.
+func (c *Clock) Advance(d time.Duration) {
+	if c.manual {
+		c.manualStamp += int64(d)
+	}
+}
+
+// now returns the current timestamp of the clock in nano seconds
+func (c *Clock) now() int64 {
+	if c.manual {
+		return c.manualStamp
+	}
+	return time.Now().UnixNano()
+}
+
 // Tick indicates a new tick/frame has occurred.
 func (c *Clock) Tick() {
-	currStamp := time.Now().UnixNano()
+	currStamp := c.now()
 
 	c.counter++
+	frames := atomic.AddUint64(&c.frames, 1)
+	if c == Time {
+		atomic.StoreUint64(&engineFrame, frames)
+	}
 
 	c.deltaStamp = currStamp - c.frameStamp
 	c.frameStamp = currStamp
@@ -46,6 +80,11 @@ func (c *Clock) Tick() {
 	}
 }
 
+// Frame is the number of ticks since the clock was created. It is safe to call from any goroutine.
+func (c *Clock) Frame() uint64 {
+	return atomic.LoadUint64(&c.frames)
+}
+
 // Delta is the amount of seconds between the last tick and the one before that
 func (c *Clock) Delta() float32 {
 	return float32(float64(c.deltaStamp) / float64(secondsInNano))
@@ -58,6 +97,6 @@ func (c *Clock) FPS() float32 {
 
 // Time is the number of seconds the clock has been running
 func (c *Clock) Time() float32 {
-	currStamp := time.Now().UnixNano()
+	currStamp := c.now()
 	return float32(float64(currStamp-c.startStamp) / float64(secondsInNano))
 }
@@ -114,16 +114,15 @@ func (m *SpaceComponent) Contains(pt image.Point) bool {
 }
 
 type mouseEntity struct {
-	*minieng.BasicEntity
 	*MouseComponent
 	*SpaceComponent
 	*RenderComponent
 }
 
-// MouseSystem listens for mouse events, and changes value for MouseComponent accordingly
+// MouseSystem listens for mouse events, and changes value for MouseComponent accordingly. It handles the
+// entities having a MouseComponent in its World, see World.Entities.
 type MouseSystem struct {
-	entities []mouseEntity
-	world    *minieng.World
+	world *minieng.World
 
 	mouseX    float32
 	mouseY    float32
@@ -144,22 +143,21 @@ func (m *MouseSystem) New(w *minieng.World) {
 //   click, etc.). If you don't need those, then you can omit the SpaceComponent.
 // * MouseComponent is always required.
 // * BasicEntity is always required.
+// The components are attached to the entity in the World, replacing the ones it had, so adding the components
+// with World.AddComponent works as well.
 func (m *MouseSystem) Add(basic *minieng.BasicEntity, mouse *MouseComponent, space *SpaceComponent, render *RenderComponent) {
-	m.entities = append(m.entities, mouseEntity{basic, mouse, space, render})
+	m.world.AddComponent(*basic, mouse)
+	if space != nil {
+		m.world.AddComponent(*basic, space)
+	}
+	if render != nil {
+		m.world.AddComponent(*basic, render)
+	}
 }
 
-// Remove ...
+// Remove detaches the MouseComponent from the entity, the other components may be used by other Systems.
 func (m *MouseSystem) Remove(basic minieng.BasicEntity) {
-	var delete = -1
-	for index, entity := range m.entities {
-		if entity.ID() == basic.ID() {
-			delete = index
-			break
-		}
-	}
-	if delete >= 0 {
-		m.entities = append(m.entities[:delete], m.entities[delete+1:]...)
-	}
+	m.world.RemoveComponent(basic, (*MouseComponent)(nil))
 }
 
 // Update ...
@@ -175,9 +173,17 @@ func (m *MouseSystem) Update(dt float32) {
 	case "Web":
 		m.mouseX = minieng.Input.Mouse.X
 		m.mouseY = minieng.Input.Mouse.Y
+	case "Headless":
+		m.mouseX = minieng.Input.Mouse.X
+		m.mouseY = minieng.Input.Mouse.Y
 	}
 
-	for _, e := range m.entities {
+	for _, basic := range m.world.Entities((*MouseComponent)(nil)) {
+		var e mouseEntity
+		m.world.Component(basic, &e.MouseComponent)
+		m.world.Component(basic, &e.SpaceComponent)
+		m.world.Component(basic, &e.RenderComponent)
+
 		// Reset all values except these
 		*e.MouseComponent = MouseComponent{
 			Track:                e.MouseComponent.Track,
@@ -0,0 +1,47 @@
+//+build headless
+
+package common
+
+import (
+	"image"
+	"testing"
+
+	"github.com/aubonbeurre/minieng"
+)
+
+func TestMouseSystem(t *testing.T) {
+	defer func(input *minieng.InputManager) { minieng.Input = input }(minieng.Input)
+	minieng.Input = minieng.NewInputManager()
+
+	w := &minieng.World{}
+	m := &MouseSystem{}
+	if err := w.AddSystem(m); err != nil {
+		t.Fatalf("AddSystem: %v", err)
+	}
+
+	button, tracker := minieng.NewBasic(), minieng.NewBasic()
+	var buttonMouse, trackerMouse MouseComponent
+	m.Add(&button, &buttonMouse, &SpaceComponent{Bounds: image.Rect(0, 0, 10, 10)}, nil)
+	// the query API finds the entities given their components directly too
+	trackerMouse.Track = true
+	w.AddComponent(tracker, &trackerMouse)
+
+	minieng.Input.Mouse.X, minieng.Input.Mouse.Y = 5, 5
+	minieng.Input.Mouse.Action = minieng.Press
+	minieng.Input.Mouse.Button = minieng.MouseButtonLeft
+	w.Update(1)
+
+	if !buttonMouse.Clicked || !buttonMouse.Enter || !buttonMouse.Hovered {
+		t.Errorf("button = %+v, want clicked, entered and hovered", buttonMouse)
+	}
+	if trackerMouse.MouseX != 5 || trackerMouse.MouseY != 5 {
+		t.Errorf("tracker at %v,%v, want 5,5", trackerMouse.MouseX, trackerMouse.MouseY)
+	}
+
+	m.Remove(button)
+	minieng.Input.Mouse.X = 50
+	w.Update(1)
+	if !buttonMouse.Hovered {
+		t.Error("the removed entity was still updated")
+	}
+}
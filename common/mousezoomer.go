@@ -1,6 +1,8 @@
 package common
 
 import (
+	"math"
+
 	"github.com/aubonbeurre/minieng"
 )
 
@@ -12,6 +14,8 @@ const (
 // MouseZoomerMessage ...
 type MouseZoomerMessage struct {
 	ScrollY float32
+	// Scale is the zoom ratio of a pinch, 1 when zooming with the scroll wheel
+	Scale float32
 }
 
 // Type ...
@@ -19,8 +23,43 @@ func (MouseZoomerMessage) Type() string {
 	return "MouseZoomerMessage"
 }
 
-// MouseZoomer is a System that allows for zooming when the scroll wheel is used
+// MouseZoomer is a System that allows for zooming when the scroll wheel is used, or when pinching on a touch screen
 type MouseZoomer struct {
+	// PinchScroll is the amount of scrolling reported for a pinch doubling the distance between the fingers.
+	// Defaults to 10.
+	PinchScroll float32
+
+	pinch   *minieng.Subscription
+	scale   float32
+	pinched bool
+	// scene is the Type of the Scene the MouseZoomer was added to, the pinches being ignored while another one is
+	// active
+	scene string
+}
+
+// New ...
+func (c *MouseZoomer) New(*minieng.World) {
+	if c.PinchScroll == 0 {
+		c.PinchScroll = 10
+	}
+	c.scale = 1
+	if scene := minieng.CurrentScene(); scene != nil {
+		c.scene = scene.Type()
+	}
+	c.pinch = minieng.Subscribe(minieng.EngineMailbox, func(msg minieng.PinchMessage) {
+		if c.scene != "" && (minieng.CurrentScene() == nil || minieng.CurrentScene().Type() != c.scene) {
+			return
+		}
+		c.scale *= msg.Scale
+		c.pinched = true
+	})
+}
+
+// Deinit ...
+func (c *MouseZoomer) Deinit(*minieng.World) {
+	if c.pinch != nil {
+		c.pinch.Cancel()
+	}
 }
 
 // Priority ...
@@ -32,6 +71,13 @@ func (*MouseZoomer) Remove(minieng.BasicEntity) {}
 // Update ...
 func (c *MouseZoomer) Update(float32) {
 	if minieng.Input.Mouse.ScrollY != 0 {
-		minieng.Mailbox.Dispatch(MouseZoomerMessage{ScrollY: minieng.Input.Mouse.ScrollY})
+		minieng.Mailbox.Dispatch(MouseZoomerMessage{ScrollY: minieng.Input.Mouse.ScrollY, Scale: 1})
+	}
+
+	if c.pinched {
+		scroll := float32(math.Log2(float64(c.scale))) * c.PinchScroll
+		minieng.Mailbox.Dispatch(MouseZoomerMessage{ScrollY: scroll, Scale: c.scale})
+		c.scale = 1
+		c.pinched = false
 	}
 }
@@ -0,0 +1,45 @@
+//+build headless
+
+package common
+
+import (
+	"testing"
+
+	"github.com/aubonbeurre/minieng"
+)
+
+// zoomerScene adds its MouseZoomer on Setup
+type zoomerScene struct {
+	name   string
+	zoomer *MouseZoomer
+}
+
+func (*zoomerScene) Preload() {}
+
+func (s *zoomerScene) Setup(w *minieng.World) {
+	if s.zoomer != nil {
+		w.AddSystem(s.zoomer)
+	}
+}
+
+func (s *zoomerScene) Type() string { return s.name }
+
+func TestMouseZoomerActiveScene(t *testing.T) {
+	zoomer := &MouseZoomer{}
+	minieng.SetScene(&zoomerScene{name: "zoomed", zoomer: zoomer}, true)
+	defer zoomer.Deinit(nil)
+
+	minieng.EngineMailbox.Dispatch(minieng.PinchMessage{Scale: 2})
+	if !zoomer.pinched || zoomer.scale != 2 {
+		t.Errorf("pinched = %v, scale = %v in the active Scene, want true, 2", zoomer.pinched, zoomer.scale)
+	}
+	zoomer.scale, zoomer.pinched = 1, false
+
+	if err := minieng.PushScene(&zoomerScene{name: "zoomer menu"}, true, minieng.Overlay{UpdateBelow: true}); err != nil {
+		t.Fatalf("PushScene: %v", err)
+	}
+	minieng.EngineMailbox.Dispatch(minieng.PinchMessage{Scale: 2})
+	if zoomer.pinched || zoomer.scale != 1 {
+		t.Errorf("pinched = %v, scale = %v while another Scene is active, want false, 1", zoomer.pinched, zoomer.scale)
+	}
+}
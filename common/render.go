@@ -52,11 +52,16 @@ func (r renderEntityList) Len() int {
 }
 
 func (r renderEntityList) Less(i, j int) bool {
-	if r[i].RenderComponent.zIndex == r[j].RenderComponent.zIndex {
-		return r[i].ID() < r[j].ID()
+	return drawnBefore(r[i], r[j])
+}
+
+// drawnBefore returns whether a is drawn before b: by zIndex, then by ID
+func drawnBefore(a, b renderEntity) bool {
+	if a.RenderComponent.zIndex == b.RenderComponent.zIndex {
+		return a.ID() < b.ID()
 	}
 
-	return r[i].RenderComponent.zIndex < r[j].RenderComponent.zIndex
+	return a.RenderComponent.zIndex < b.RenderComponent.zIndex
 }
 
 func (r renderEntityList) Swap(i, j int) {
@@ -66,15 +71,20 @@ func (r renderEntityList) Swap(i, j int) {
 // RenderSystem ...
 type RenderSystem struct {
 	entities renderEntityList
+	index    map[uint64]int
 	world    *minieng.World
 
 	sortingNeeded bool
+	changes       *minieng.Subscription
 	//currentShader Shader
 }
 
 // Priority ...
 func (*RenderSystem) Priority() int { return RenderSystemPriority }
 
+// Renderer marks the RenderSystem as drawing the World
+func (*RenderSystem) Renderer() {}
+
 // New ...
 func (rs *RenderSystem) New(w *minieng.World) {
 	rs.world = w
@@ -84,16 +94,42 @@ func (rs *RenderSystem) New(w *minieng.World) {
 	//initShaders(w)
 	//engo.Gl.Enable(engo.Gl.MULTISAMPLE)
 
-	minieng.Mailbox.Listen("renderChangeMessage", func(minieng.Message) {
+	rs.changes = minieng.Mailbox.Listen("renderChangeMessage", func(minieng.Message) {
 		rs.sortingNeeded = true
 	})
 }
 
-// Add ...
+// Add adds the entity at its place in the drawing order. An entity which was already added gets the new
+// RenderComponent, the Drawable of the previous one being deleted.
 func (rs *RenderSystem) Add(basic *minieng.BasicEntity, render *RenderComponent) {
-	rs.entities = append(rs.entities, renderEntity{BasicEntity: basic, RenderComponent: render})
+	rs.Remove(*basic)
+	if rs.index == nil {
+		rs.index = make(map[uint64]int)
+	}
+
+	e := renderEntity{BasicEntity: basic, RenderComponent: render}
+	i := sort.Search(len(rs.entities), func(i int) bool {
+		return drawnBefore(e, rs.entities[i])
+	})
+	rs.entities = append(rs.entities, renderEntity{})
+	copy(rs.entities[i+1:], rs.entities[i:])
+	rs.entities[i] = e
+	rs.reindex(i)
+
 	render.Drawable.Setup()
-	rs.sortingNeeded = true
+}
+
+// reindex updates the index of the entities from i on, after they moved
+func (rs *RenderSystem) reindex(i int) {
+	for ; i < len(rs.entities); i++ {
+		rs.index[rs.entities[i].ID()] = i
+	}
+}
+
+// Deinit deletes the Drawables of all the entities, when the RenderSystem is removed from its World
+func (rs *RenderSystem) Deinit(*minieng.World) {
+	rs.changes.Cancel()
+	rs.RemoveAll()
 }
 
 // RemoveAll ...
@@ -105,29 +141,26 @@ func (rs *RenderSystem) RemoveAll() {
 
 // Remove ...
 func (rs *RenderSystem) Remove(basic minieng.BasicEntity) {
-	var delete = -1
-	for index, entity := range rs.entities {
-		if entity.ID() == basic.ID() {
-			delete = index
-			break
-		}
-	}
-	if delete >= 0 {
-		rs.entities[delete].Drawable.Delete()
-		rs.entities = append(rs.entities[:delete], rs.entities[delete+1:]...)
-		rs.sortingNeeded = true
+	i, ok := rs.index[basic.ID()]
+	if !ok {
+		return
 	}
+	rs.entities[i].Drawable.Delete()
+
+	// the entities after the removed one keep their order, removing the last one is cheap
+	rs.entities = append(rs.entities[:i], rs.entities[i+1:]...)
+	delete(rs.index, basic.ID())
+	rs.reindex(i)
 }
 
-// Update ...
+// Update draws the entities. The framebuffer is cleared by the engine beforehand, so Scenes rendered below an
+// Overlay remain visible. The entities are only sorted again after a call to SetZIndex.
 func (rs *RenderSystem) Update(dt float32) {
 	if rs.sortingNeeded {
 		sort.Sort(rs.entities)
+		rs.reindex(0)
 		rs.sortingNeeded = false
 	}
-	Gl := glplus.Gl
-	Gl.Clear(Gl.COLOR_BUFFER_BIT | Gl.DEPTH_BUFFER_BIT)
-
 	for _, e := range rs.entities {
 		if e.RenderComponent.Hidden {
 			continue // with other entities
@@ -143,5 +176,8 @@ func SetBackground(c color.Color) {
 	r, g, b, a := c.RGBA()
 
 	Gl := glplus.Gl
+	if Gl == nil {
+		return
+	}
 	Gl.ClearColor(float32(r)/0xffff, float32(g)/0xffff, float32(b)/0xffff, float32(a)/0xffff)
 }
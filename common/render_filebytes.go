@@ -24,12 +24,25 @@ type stuffLoader struct {
 }
 
 func (i *stuffLoader) Load(url string, data io.Reader) error {
+	buf, err := i.Decode(url, data)
+	if err != nil {
+		return err
+	}
+	return i.Upload(url, buf)
+}
+
+// Decode reads the data, it can be called from any goroutine
+func (i *stuffLoader) Decode(url string, data io.Reader) (interface{}, error) {
 	buf := &bytes.Buffer{}
 	if _, err := buf.ReadFrom(data); err != nil {
-		return err
+		return nil, err
 	}
+	return buf, nil
+}
 
-	i.bytes[url] = NewBytesResource(buf)
+// Upload keeps the data returned by Decode
+func (i *stuffLoader) Upload(url string, decoded interface{}) error {
+	i.bytes[url] = NewBytesResource(decoded.(*bytes.Buffer))
 
 	return nil
 }
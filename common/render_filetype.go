@@ -30,16 +30,30 @@ type imageLoader struct {
 }
 
 func (i *imageLoader) Load(url string, data io.Reader) error {
-	img, _, err := image.Decode(data)
+	img, err := i.Decode(url, data)
 	if err != nil {
 		return err
 	}
+	return i.Upload(url, img)
+}
+
+// Decode converts the image to RGBA, it can be called from any goroutine
+func (i *imageLoader) Decode(url string, data io.Reader) (interface{}, error) {
+	img, _, err := image.Decode(data)
+	if err != nil {
+		return nil, err
+	}
 
 	b := img.Bounds()
 	newm := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
 	draw.Draw(newm, newm.Bounds(), img, b.Min, draw.Src)
 
-	i.images[url] = NewTextureResource(newm)
+	return newm, nil
+}
+
+// Upload sends the image returned by Decode to the GPU
+func (i *imageLoader) Upload(url string, decoded interface{}) error {
+	i.images[url] = NewTextureResource(decoded.(*image.RGBA))
 
 	return nil
 }
@@ -60,6 +74,10 @@ func (i *imageLoader) Resource(url string) (minieng.Resource, error) {
 
 // NewTextureResource sends the image to the GPU and returns a `TextureResource` for easy access
 func NewTextureResource(img *image.RGBA) TextureResource {
+	if glplus.Gl == nil {
+		// headless: keep the image around, there is no GPU to send it to
+		return TextureResource{Img: img}
+	}
 	if t, err := glplus.NewRGBATexture(img, true, false); err != nil {
 		panic(err)
 	} else {
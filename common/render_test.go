@@ -0,0 +1,81 @@
+//+build headless
+
+package common
+
+import (
+	"testing"
+
+	"github.com/aubonbeurre/minieng"
+)
+
+// testDrawable counts the calls to its methods
+type testDrawable struct {
+	setup, deleted int
+}
+
+func (d *testDrawable) Setup()       { d.setup++ }
+func (d *testDrawable) Draw(float32) {}
+func (d *testDrawable) Delete()      { d.deleted++ }
+
+// order returns the IDs of the entities of the RenderSystem, in drawing order, checking the index
+func order(t *testing.T, rs *RenderSystem) []uint64 {
+	var ids []uint64
+	for i, e := range rs.entities {
+		if rs.index[e.ID()] != i {
+			t.Errorf("entity %d is at %d, indexed at %d", e.ID(), i, rs.index[e.ID()])
+		}
+		ids = append(ids, e.ID())
+	}
+	if len(rs.index) != len(rs.entities) {
+		t.Errorf("%d entities indexed, want %d", len(rs.index), len(rs.entities))
+	}
+	return ids
+}
+
+func TestRenderSystemOrder(t *testing.T) {
+	rs := &RenderSystem{}
+	entities := minieng.NewBasics(4)
+	for i, z := range []float32{2, 1, 2, 0} {
+		rs.Add(&entities[i], &RenderComponent{Drawable: &testDrawable{}, zIndex: z})
+	}
+
+	want := []uint64{entities[3].ID(), entities[1].ID(), entities[0].ID(), entities[2].ID()}
+	if got := order(t, rs); !equalIDs(got, want) {
+		t.Errorf("drawing order = %v, want %v", got, want)
+	}
+
+	rs.Remove(entities[1])
+	want = []uint64{entities[3].ID(), entities[0].ID(), entities[2].ID()}
+	if got := order(t, rs); !equalIDs(got, want) || rs.sortingNeeded {
+		t.Errorf("drawing order after Remove = %v, want %v without sorting", got, want)
+	}
+}
+
+func TestRenderSystemAddTwice(t *testing.T) {
+	rs := &RenderSystem{}
+	entities := minieng.NewBasics(2)
+	first, second := &testDrawable{}, &testDrawable{}
+	rs.Add(&entities[0], &RenderComponent{Drawable: first, zIndex: 0})
+	rs.Add(&entities[1], &RenderComponent{Drawable: &testDrawable{}, zIndex: 1})
+	rs.Add(&entities[0], &RenderComponent{Drawable: second, zIndex: 2})
+
+	want := []uint64{entities[1].ID(), entities[0].ID()}
+	if got := order(t, rs); !equalIDs(got, want) {
+		t.Errorf("drawing order = %v, want %v", got, want)
+	}
+	if first.deleted != 1 || second.setup != 1 {
+		t.Errorf("replaced Drawable deleted %d times, new one set up %d times, want 1 and 1", first.deleted, second.setup)
+	}
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package minieng
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// components stores the components of the entities of a World, indexed by
+// their type and then by entity ID.
+type components map[reflect.Type]map[uint64]interface{}
+
+// AddComponent attaches the given component to the entity. Components are
+// stored by type, so an entity holds at most one component of a given type
+// and adding another one replaces it. Components are usually pointers, so that
+// Systems can modify them in place:
+//
+//    w.AddComponent(basic, &common.SpaceComponent{Bounds: bounds})
+func (w *World) AddComponent(e BasicEntity, component interface{}) {
+	if w.components == nil {
+		w.components = make(components)
+	}
+
+	t := reflect.TypeOf(component)
+	store, ok := w.components[t]
+	if !ok {
+		store = make(map[uint64]interface{})
+		w.components[t] = store
+	}
+	store[e.ID()] = component
+}
+
+// Component looks up the component of the entity which has the type pointed to
+// by ptr, and stores it in ptr. It returns false if the entity has no such
+// component:
+//
+//    var space *common.SpaceComponent
+//    if w.Component(basic, &space) {
+//        ...
+//    }
+func (w *World) Component(e BasicEntity, ptr interface{}) bool {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("Component needs a non-nil pointer, got %T", ptr))
+	}
+
+	component, ok := w.components[v.Type().Elem()][e.ID()]
+	if ok {
+		v.Elem().Set(reflect.ValueOf(component))
+	}
+	return ok
+}
+
+// HasComponent returns whether the entity has a component of the same type as
+// the given one, i.e. `(*common.SpaceComponent)(nil)`.
+func (w *World) HasComponent(e BasicEntity, component interface{}) bool {
+	_, ok := w.components[reflect.TypeOf(component)][e.ID()]
+	return ok
+}
+
+// RemoveComponent detaches the component of the same type as the given one,
+// i.e. `(*common.SpaceComponent)(nil)`, from the entity.
+func (w *World) RemoveComponent(e BasicEntity, component interface{}) {
+	delete(w.components[reflect.TypeOf(component)], e.ID())
+}
+
+// Entities returns the entities having a component of every one of the given
+// types, sorted by ID:
+//
+//    for _, e := range w.Entities((*common.MouseComponent)(nil), (*common.SpaceComponent)(nil)) {
+//        ...
+//    }
+func (w *World) Entities(componentTypes ...interface{}) []BasicEntity {
+	if len(componentTypes) == 0 {
+		return nil
+	}
+
+	// iterate over the smallest store, and check the others
+	stores := make([]map[uint64]interface{}, len(componentTypes))
+	smallest := 0
+	for i, c := range componentTypes {
+		stores[i] = w.components[reflect.TypeOf(c)]
+		if len(stores[i]) < len(stores[smallest]) {
+			smallest = i
+		}
+	}
+
+	var entities []BasicEntity
+Outer:
+	for id := range stores[smallest] {
+		for _, store := range stores {
+			if _, ok := store[id]; !ok {
+				continue Outer
+			}
+		}
+		entities = append(entities, BasicEntity{id: id})
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].id < entities[j].id
+	})
+	return entities
+}
+
+// removeAll detaches every component from the entity.
+func (c components) removeAll(e BasicEntity) {
+	for _, store := range c {
+		delete(store, e.ID())
+	}
+}
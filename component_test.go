@@ -0,0 +1,59 @@
+//+build headless
+
+package minieng
+
+import (
+	"reflect"
+	"testing"
+)
+
+type positionTestComponent struct{ X, Y float32 }
+
+type velocityTestComponent struct{ X, Y float32 }
+
+func TestComponents(t *testing.T) {
+	w := &World{}
+	e := NewBasic()
+
+	w.AddComponent(e, &positionTestComponent{X: 1})
+	w.AddComponent(e, &positionTestComponent{X: 2})
+
+	var position *positionTestComponent
+	if !w.Component(e, &position) || position.X != 2 {
+		t.Errorf("Component = %v, want the component added last", position)
+	}
+	if w.HasComponent(e, (*velocityTestComponent)(nil)) {
+		t.Error("HasComponent found a component which was never added")
+	}
+
+	w.RemoveComponent(e, (*positionTestComponent)(nil))
+	if w.HasComponent(e, (*positionTestComponent)(nil)) {
+		t.Error("the component is still attached after RemoveComponent")
+	}
+}
+
+func TestEntitiesQuery(t *testing.T) {
+	w := &World{}
+	entities := NewBasics(4)
+	for _, e := range entities {
+		w.AddComponent(e, &positionTestComponent{})
+	}
+	for _, e := range []BasicEntity{entities[3], entities[0], entities[2]} {
+		w.AddComponent(e, &velocityTestComponent{})
+	}
+
+	got := w.Entities((*positionTestComponent)(nil), (*velocityTestComponent)(nil))
+	if want := []BasicEntity{entities[0], entities[2], entities[3]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Entities = %v, want %v", got, want)
+	}
+
+	w.RemoveEntity(entities[2])
+	got = w.Entities((*velocityTestComponent)(nil))
+	if want := []BasicEntity{entities[0], entities[3]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Entities after RemoveEntity = %v, want %v", got, want)
+	}
+
+	if got := w.Entities(); got != nil {
+		t.Errorf("Entities() = %v, want nil", got)
+	}
+}
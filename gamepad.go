@@ -0,0 +1,531 @@
+package minieng
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// MaxGamepads is the number of gamepads tracked by the InputManager.
+const MaxGamepads = 16
+
+// maxGamepadHats is the number of hats a gamepad mapping may use
+const maxGamepadHats = 4
+
+// GamepadButton is a button of a gamepad, laid out like an Xbox controller.
+type GamepadButton int
+
+// Gamepad buttons
+const (
+	GamepadA GamepadButton = iota
+	GamepadB
+	GamepadX
+	GamepadY
+	GamepadLeftBumper
+	GamepadRightBumper
+	GamepadBack
+	GamepadStart
+	GamepadGuide
+	GamepadLeftThumb
+	GamepadRightThumb
+	GamepadDpadUp
+	GamepadDpadRight
+	GamepadDpadDown
+	GamepadDpadLeft
+
+	gamepadButtonCount = iota
+)
+
+// GamepadAxis is an axis of a gamepad. Sticks go from AxisMin to AxisMax, with the Y axes pointing down, and
+// triggers from AxisNeutral (released) to AxisMax.
+type GamepadAxis int
+
+// Gamepad axes
+const (
+	GamepadLeftX GamepadAxis = iota
+	GamepadLeftY
+	GamepadRightX
+	GamepadRightY
+	GamepadLeftTrigger
+	GamepadRightTrigger
+
+	gamepadAxisCount = iota
+)
+
+var (
+	gamepadButtonNames = [gamepadButtonCount]string{
+		"A", "B", "X", "Y", "LeftBumper", "RightBumper", "Back", "Start", "Guide", "LeftThumb", "RightThumb",
+		"DpadUp", "DpadRight", "DpadDown", "DpadLeft",
+	}
+	gamepadAxisNames = [gamepadAxisCount]string{"LeftX", "LeftY", "RightX", "RightY", "LeftTrigger", "RightTrigger"}
+)
+
+// String returns the name of the button, i.e. "A" or "DpadUp".
+func (b GamepadButton) String() string {
+	if b < 0 || b >= gamepadButtonCount {
+		return fmt.Sprintf("GamepadButton(%d)", int(b))
+	}
+	return gamepadButtonNames[b]
+}
+
+// String returns the name of the axis, i.e. "LeftX" or "RightTrigger".
+func (a GamepadAxis) String() string {
+	if a < 0 || a >= gamepadAxisCount {
+		return fmt.Sprintf("GamepadAxis(%d)", int(a))
+	}
+	return gamepadAxisNames[a]
+}
+
+// gamepadKeyBase is the first Key used for the gamepad buttons, far above the keyboard keys
+const gamepadKeyBase Key = 1 << 16
+
+// GamepadKey returns the Key standing for a button of the given gamepad, so that it can be used as a Button
+// trigger like any keyboard key:
+//
+//    minieng.Input.RegisterButton("jump", minieng.Space, minieng.GamepadKey(0, minieng.GamepadA))
+func GamepadKey(id int, button GamepadButton) Key {
+	return gamepadKeyBase + Key(id*gamepadButtonCount+int(button))
+}
+
+// Gamepad holds the state of a gamepad, its buttons being mapped to Keys by GamepadKey.
+type Gamepad struct {
+	// ID is the index of the gamepad, from 0 to MaxGamepads-1
+	ID int
+	// Name is the name reported by the device
+	Name string
+	// Connected is false when no gamepad uses this index
+	Connected bool
+
+	axes    [gamepadAxisCount]float32
+	buttons [gamepadButtonCount]bool
+	mapping *gamepadMapping
+	blocked bool
+
+	// keys is the KeyManager of the InputManager owning the gamepad
+	keys *KeyManager
+}
+
+// Axis returns the current value of the given axis.
+func (g *Gamepad) Axis(axis GamepadAxis) float32 {
+	if g.blocked || axis < 0 || axis >= gamepadAxisCount {
+		return AxisNeutral
+	}
+	return g.axes[axis]
+}
+
+// Button returns the state of the given button.
+func (g *Gamepad) Button(button GamepadButton) KeyState {
+	if g.keys == nil {
+		return KeyState{}
+	}
+	return g.keys.Get(GamepadKey(g.ID, button))
+}
+
+// Gamepad returns the gamepad with the given index, or nil if the index is out of range. A gamepad which isn't
+// plugged in is returned too, with Connected being false.
+func (im *InputManager) Gamepad(id int) *Gamepad {
+	if id < 0 || id >= MaxGamepads {
+		return nil
+	}
+	g := &im.gamepads[id]
+	g.keys = im.keys
+	return g
+}
+
+// setGamepad updates the state of a gamepad from the raw values reported by the device, buttons ranging from 0 to
+// 1. standard is true when the device already uses the standard layout of the browser Gamepad API.
+func (im *InputManager) setGamepad(id int, name string, standard bool, axes, buttons []float32) {
+	g := &im.gamepads[id]
+	if !g.Connected || g.Name != name {
+		g.ID = id
+		g.Name = name
+		g.Connected = true
+		g.mapping = findGamepadMapping(name, standard)
+	}
+	g.keys = im.keys
+
+	hats := g.mapping.hatStates(axes, buttons)
+	for axis := range g.axes {
+		g.axes[axis] = g.mapping.axisValue(GamepadAxis(axis), axes, buttons, hats)
+	}
+	for button, down := range g.buttons {
+		if pressed := g.mapping.buttons[button].value(axes, buttons, hats) > 0.5; pressed != down {
+			g.buttons[button] = pressed
+			im.keys.Set(GamepadKey(id, GamepadButton(button)), pressed)
+		}
+	}
+}
+
+// state returns the recorded state of the gamepad with the given index.
+func (g *Gamepad) state(id int) gamepadState {
+	return gamepadState{ID: id, Name: g.Name, Connected: g.Connected, Axes: g.axes}
+}
+
+// disconnectGamepad releases the buttons of a gamepad which got unplugged.
+func (im *InputManager) disconnectGamepad(id int) {
+	g := &im.gamepads[id]
+	if !g.Connected {
+		return
+	}
+
+	for button, down := range g.buttons {
+		if down {
+			im.keys.Set(GamepadKey(id, GamepadButton(button)), false)
+		}
+	}
+	*g = Gamepad{ID: id, keys: im.keys}
+}
+
+// GamepadConnectedMessage is a message that's being dispatched whenever a gamepad is plugged in or unplugged. It
+// is posted to EngineMailbox.
+type GamepadConnectedMessage struct {
+	ID        int
+	Name      string
+	Connected bool
+}
+
+// Type returns the type of the current object "GamepadConnectedMessage"
+func (GamepadConnectedMessage) Type() string { return "GamepadConnectedMessage" }
+
+// AxisGamepad is an AxisPair for an axis of a gamepad. Values within the Deadzone are reported as AxisNeutral, the
+// others are scaled so the axis still reaches AxisMin and AxisMax.
+type AxisGamepad struct {
+	ID       int
+	Axis     GamepadAxis
+	Deadzone float32
+	// Invert flips the direction of the axis, i.e. to have the Y axes point up
+	Invert bool
+}
+
+// Value returns the value of the gamepad axis.
+func (ag AxisGamepad) Value() float32 {
+	g := Input.Gamepad(ag.ID)
+	if g == nil {
+		return AxisNeutral
+	}
+
+	v := g.Axis(ag.Axis)
+	if ag.Invert {
+		v = -v
+	}
+
+	switch {
+	case ag.Deadzone >= 1:
+		return AxisNeutral
+	case v > ag.Deadzone:
+		return (v - ag.Deadzone) / (1 - ag.Deadzone)
+	case v < -ag.Deadzone:
+		return (v + ag.Deadzone) / (1 - ag.Deadzone)
+	}
+	return AxisNeutral
+}
+
+// gamepadSource is a raw button, axis or hat direction of a device, as found in an SDL mapping
+type gamepadSource struct {
+	valid  bool
+	axis   bool
+	hat    bool
+	index  int
+	mask   int // the direction of a hat, 1 for up, 2 for right, 4 for down and 8 for left
+	half   int // 1 for the positive half of an axis, -1 for the negative half, 0 for the full range
+	invert bool
+}
+
+// value returns the value of the source, hats holding the state of the hats of the device.
+func (s gamepadSource) value(axes, buttons []float32, hats []int) float32 {
+	if !s.valid {
+		return 0
+	}
+
+	var v float32
+	if s.hat {
+		if s.index < len(hats) && hats[s.index]&s.mask != 0 {
+			v = 1
+		}
+	} else if s.axis {
+		if s.index < len(axes) {
+			v = axes[s.index]
+		}
+	} else if s.index < len(buttons) {
+		v = buttons[s.index]
+	}
+
+	if s.invert {
+		v = -v
+	}
+	switch {
+	case s.half > 0 && v < 0, s.half < 0 && v > 0:
+		v = 0
+	case s.half < 0:
+		v = -v
+	}
+	return v
+}
+
+// axisValue returns the value of the source for the given axis, bringing full range axes used as triggers within
+// [0, 1].
+func (s gamepadSource) axisValue(axis GamepadAxis, axes, buttons []float32, hats []int) float32 {
+	if axis == GamepadLeftTrigger || axis == GamepadRightTrigger {
+		return s.unitValue(axes, buttons, hats)
+	}
+	return s.value(axes, buttons, hats)
+}
+
+// unitValue returns the value of the source within [0, 1], for the outputs which have a single direction.
+func (s gamepadSource) unitValue(axes, buttons []float32, hats []int) float32 {
+	v := s.value(axes, buttons, hats)
+	if s.axis && s.half == 0 {
+		v = (v + 1) / 2
+	}
+	return v
+}
+
+// gamepadMapping maps the raw buttons, axes and hats of a device to the gamepad layout
+type gamepadMapping struct {
+	name    string
+	buttons [gamepadButtonCount]gamepadSource
+	axes    [gamepadAxisCount]gamepadSource
+	// halfAxes drive the positive and the negative half of the axes, as in "+leftx:b2,-leftx:b3"
+	halfAxes [gamepadAxisCount][2]gamepadSource
+	hats     int
+}
+
+// axisValue returns the value of the given axis, from its source and the ones of its halves.
+func (m *gamepadMapping) axisValue(axis GamepadAxis, axes, buttons []float32, hats []int) float32 {
+	v := m.axes[axis].axisValue(axis, axes, buttons, hats)
+	halves := m.halfAxes[axis]
+	return v + halves[0].unitValue(axes, buttons, hats) - halves[1].unitValue(axes, buttons, hats)
+}
+
+// hatsAsAxes is true where GLFW reports the hats as two axes each rather than four buttons
+var hatsAsAxes = runtime.GOOS == "linux"
+
+// hatStates returns the state of the hats used by the mapping, as the bit masks of the SDL mappings. GLFW 3.2 has
+// no hat API: the hats come last in the raw data, as four buttons each (up, right, down, left), or two axes each
+// (x, y) on Linux.
+func (m *gamepadMapping) hatStates(axes, buttons []float32) []int {
+	if m.hats == 0 {
+		return nil
+	}
+
+	hats := make([]int, m.hats)
+	for h := range hats {
+		if hatsAsAxes {
+			i := len(axes) - 2*(m.hats-h)
+			if i < 0 {
+				continue
+			}
+			switch x := axes[i]; {
+			case x > 0.5:
+				hats[h] |= 2
+			case x < -0.5:
+				hats[h] |= 8
+			}
+			switch y := axes[i+1]; {
+			case y < -0.5:
+				hats[h] |= 1
+			case y > 0.5:
+				hats[h] |= 4
+			}
+			continue
+		}
+
+		i := len(buttons) - 4*(m.hats-h)
+		if i < 0 {
+			continue
+		}
+		for bit := 0; bit < 4; bit++ {
+			if buttons[i+bit] > 0.5 {
+				hats[h] |= 1 << uint(bit)
+			}
+		}
+	}
+	return hats
+}
+
+var (
+	// sdlPlatform is the name of the current platform in the SDL mappings
+	sdlPlatform = map[string]string{
+		"windows": "Windows", "darwin": "Mac OS X", "linux": "Linux", "android": "Android", "ios": "iOS",
+	}[runtime.GOOS]
+
+	// gamepadMappings holds the mappings added by AddGamepadMappings, by name
+	gamepadMappings = make(map[string]*gamepadMapping)
+
+	// sdlButtons and sdlAxes are the names used by the SDL mappings
+	sdlButtons = map[string]GamepadButton{
+		"a": GamepadA, "b": GamepadB, "x": GamepadX, "y": GamepadY,
+		"leftshoulder": GamepadLeftBumper, "rightshoulder": GamepadRightBumper,
+		"back": GamepadBack, "start": GamepadStart, "guide": GamepadGuide,
+		"leftstick": GamepadLeftThumb, "rightstick": GamepadRightThumb,
+		"dpup": GamepadDpadUp, "dpright": GamepadDpadRight, "dpdown": GamepadDpadDown, "dpleft": GamepadDpadLeft,
+	}
+	sdlAxes = map[string]GamepadAxis{
+		"leftx": GamepadLeftX, "lefty": GamepadLeftY, "rightx": GamepadRightX, "righty": GamepadRightY,
+		"lefttrigger": GamepadLeftTrigger, "righttrigger": GamepadRightTrigger,
+	}
+
+	// rawMapping is used for the unknown devices: the raw buttons and axes are taken in the gamepad order
+	rawMapping = func() *gamepadMapping {
+		m := &gamepadMapping{}
+		for i := range m.buttons {
+			m.buttons[i] = gamepadSource{valid: true, index: i}
+		}
+		for i := range m.axes {
+			m.axes[i] = gamepadSource{valid: true, axis: true, index: i}
+		}
+		return m
+	}()
+
+	// standardMapping is the standard layout of the browser Gamepad API, with the triggers reported as buttons
+	standardMapping = func() *gamepadMapping {
+		m := &gamepadMapping{}
+		for button, index := range []int{0, 1, 2, 3, 4, 5, 8, 9, 16, 10, 11, 12, 15, 13, 14} {
+			m.buttons[button] = gamepadSource{valid: true, index: index}
+		}
+		for axis := GamepadLeftX; axis <= GamepadRightY; axis++ {
+			m.axes[axis] = gamepadSource{valid: true, axis: true, index: int(axis)}
+		}
+		m.axes[GamepadLeftTrigger] = gamepadSource{valid: true, index: 6}
+		m.axes[GamepadRightTrigger] = gamepadSource{valid: true, index: 7}
+		return m
+	}()
+)
+
+// findGamepadMapping returns the mapping of the device with the given name.
+func findGamepadMapping(name string, standard bool) *gamepadMapping {
+	if m, ok := gamepadMappings[name]; ok {
+		return m
+	}
+	if standard {
+		return standardMapping
+	}
+	return rawMapping
+}
+
+// AddGamepadMappings adds mappings in the SDL gamecontroller format, one per line, i.e. from the
+// gamecontrollerdb.txt of the SDL community:
+//
+//    030000005e0400008e02000014010000,Xbox 360 Controller,a:b0,b:b1,x:b2,y:b3,...,platform:Linux,
+//
+// The devices are matched by name, as GLFW 3.2 doesn't report their GUID. Hats, which usually hold the d-pad, are
+// read from the end of the raw buttons, or of the raw axes on Linux, as GLFW 3.2 reports them there. Devices
+// without a mapping use the standard layout on the web, and their raw buttons and axes in the gamepad order
+// elsewhere. The mappings given for another platform are skipped.
+func AddGamepadMappings(mappings string) error {
+	for i, line := range strings.Split(mappings, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m, err := parseGamepadMapping(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if m != nil {
+			gamepadMappings[m.name] = m
+		}
+	}
+	return nil
+}
+
+// parseGamepadMapping parses a single SDL mapping. It returns nil when the mapping is for another platform.
+func parseGamepadMapping(line string) (*gamepadMapping, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid gamepad mapping %q", line)
+	}
+
+	m := &gamepadMapping{name: fields[1]}
+	for _, field := range fields[2:] {
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid gamepad mapping element %q", field)
+		}
+		if parts[0] == "platform" {
+			if parts[1] != sdlPlatform {
+				return nil, nil
+			}
+			continue
+		}
+
+		source, err := parseGamepadSource(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if source.hat && source.index >= m.hats {
+			m.hats = source.index + 1
+		}
+		output := parts[0]
+		half := -1
+		switch {
+		case strings.HasPrefix(output, "+"):
+			half, output = 0, output[1:]
+		case strings.HasPrefix(output, "-"):
+			half, output = 1, output[1:]
+		}
+		if button, ok := sdlButtons[output]; ok && half < 0 {
+			m.buttons[button] = source
+		} else if axis, ok := sdlAxes[output]; ok {
+			if half < 0 {
+				m.axes[axis] = source
+			} else {
+				m.halfAxes[axis][half] = source
+			}
+		}
+	}
+	return m, nil
+}
+
+// parseGamepadSource parses a raw input of an SDL mapping, i.e. "b0", "a1", "+a2", "a3~" or "h0.4".
+func parseGamepadSource(s string) (gamepadSource, error) {
+	source := gamepadSource{valid: true}
+
+	raw := s
+	if strings.HasPrefix(s, "h") {
+		parts := strings.SplitN(s[1:], ".", 2)
+		if len(parts) != 2 {
+			return source, fmt.Errorf("invalid gamepad input %q", raw)
+		}
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 || index >= maxGamepadHats {
+			return source, fmt.Errorf("invalid gamepad input %q", raw)
+		}
+		mask, err := strconv.Atoi(parts[1])
+		if err != nil || mask <= 0 || mask > 8 || mask&(mask-1) != 0 {
+			return source, fmt.Errorf("invalid gamepad input %q", raw)
+		}
+		source.hat, source.index, source.mask = true, index, mask
+		return source, nil
+	}
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+		source.half, s = 1, s[1:]
+	case strings.HasPrefix(s, "-"):
+		source.half, s = -1, s[1:]
+	}
+	if strings.HasSuffix(s, "~") {
+		source.invert, s = true, s[:len(s)-1]
+	}
+
+	switch {
+	case strings.HasPrefix(s, "a"):
+		source.axis = true
+	case strings.HasPrefix(s, "b"):
+	default:
+		return source, fmt.Errorf("invalid gamepad input %q", raw)
+	}
+
+	index, err := strconv.Atoi(s[1:])
+	if err != nil || index < 0 {
+		return source, fmt.Errorf("invalid gamepad input %q", raw)
+	}
+	source.index = index
+	return source, nil
+}
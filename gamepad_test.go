@@ -0,0 +1,76 @@
+//+build headless
+
+package minieng
+
+import "testing"
+
+func TestAddGamepadMappings(t *testing.T) {
+	defer func(platform string, asAxes bool) {
+		sdlPlatform, hatsAsAxes = platform, asAxes
+		gamepadMappings = make(map[string]*gamepadMapping)
+	}(sdlPlatform, hatsAsAxes)
+	sdlPlatform, hatsAsAxes = "Linux", false
+
+	err := AddGamepadMappings(`
+# the mapping of another platform is skipped
+03000000000000000000000000000000,Test Pad,a:b3,platform:Windows,
+03000000000000000000000000000000,Test Pad,a:b0,b:b1,dpup:h0.1,leftx:a0,lefty:a1~,lefttrigger:a2,righttrigger:+a3,-rightx:b2,+rightx:b3,platform:Linux,
+03000000000000000000000000000000,Other Pad,a:b1,
+`)
+	if err != nil {
+		t.Fatalf("AddGamepadMappings: %v", err)
+	}
+	if _, ok := gamepadMappings["Other Pad"]; !ok {
+		t.Error("the mapping without a platform was skipped")
+	}
+
+	im := NewInputManager()
+	// 4 buttons, then the 4 buttons of the hat: up is down
+	im.setGamepad(0, "Test Pad", false, []float32{0.5, 0.25, 0, -0.5}, []float32{1, 0, 0, 1, 1, 0, 0, 0})
+	im.update()
+	g := im.Gamepad(0)
+
+	for _, test := range []struct {
+		axis GamepadAxis
+		want float32
+	}{
+		{GamepadLeftX, 0.5},
+		{GamepadLeftY, -0.25},
+		{GamepadLeftTrigger, 0.5},
+		{GamepadRightTrigger, 0},
+		{GamepadRightX, 1},
+	} {
+		if v := g.Axis(test.axis); v != test.want {
+			t.Errorf("Axis(%v) = %v, want %v", test.axis, v, test.want)
+		}
+	}
+	for _, test := range []struct {
+		button GamepadButton
+		want   bool
+	}{
+		{GamepadA, true},
+		{GamepadB, false},
+		{GamepadDpadUp, true},
+	} {
+		if down := g.Button(test.button).Down(); down != test.want {
+			t.Errorf("Button(%v).Down() = %v, want %v", test.button, down, test.want)
+		}
+	}
+
+	im.setGamepad(0, "Test Pad", false, []float32{0, 0, 0, 0}, []float32{0, 0, 1, 0, 0, 0, 0, 0})
+	im.update()
+	if v := g.Axis(GamepadRightX); v != -1 {
+		t.Errorf("Axis(RightX) = %v with its negative half pressed, want -1", v)
+	}
+}
+
+func TestParseGamepadSourceErrors(t *testing.T) {
+	for _, s := range []string{"", "c0", "b", "b-1", "h0", "h0.3", "h9.1", "ax"} {
+		if _, err := parseGamepadSource(s); err == nil {
+			t.Errorf("parseGamepadSource(%q) succeeded", s)
+		}
+	}
+	if err := AddGamepadMappings("0300,Broken Pad,a:x0,"); err == nil {
+		t.Error("AddGamepadMappings succeeded with an invalid input")
+	}
+}
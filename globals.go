@@ -7,10 +7,18 @@ var (
 	// Mailbox is used by all Systems to communicate
 	Mailbox *MessageManager
 
+	// EngineMailbox receives the platform events, such as WindowResizeMessage. Unlike Mailbox, it is the same for
+	// every Scene, so subscriptions survive scene switches; Systems subscribing to it should cancel their
+	// Subscription in Deinit.
+	EngineMailbox = &MessageManager{}
+
 	currentWorld *World
 	currentScene Scene
 
-	closeGame bool
+	// closeGame is set by Exit, from any goroutine, see closing
+	closeGame int32
+
+	opts RunOptions
 
 	// Time ...
 	Time *Clock
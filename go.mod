@@ -1,17 +1,28 @@
 module github.com/aubonbeurre/minieng
 
-go 1.16
+go 1.18
 
 require (
 	github.com/aubonbeurre/glplus v0.0.3
 	github.com/go-gl/glfw3 v0.0.0-20210410170116-ea3d685f79fb
 	github.com/gopherjs/gopherjs v0.0.0-20210621113107-84c6004145de
 	github.com/inkyblackness/imgui-go v1.12.0
-	golang.org/x/exp v0.0.0-20210625193404-fa9d1d177d71 // indirect
 	golang.org/x/mobile v0.0.0-20210614202936-7c8f154d1008
+	gopkg.in/yaml.v2 v2.4.0
 	honnef.co/go/js/dom v0.0.0-20200509013220-d4405f7ab4d8
-	honnef.co/go/js/util v0.0.0-20150216223935-96b8dd9d1621 // indirect
 	honnef.co/go/js/xhr v0.0.0-20150307031022-00e3346113ae
 )
 
+require (
+	github.com/aubonbeurre/go-obj v0.4.0 // indirect
+	github.com/go-gl/gl v0.0.0-20210501111010-69f74958bac0 // indirect
+	github.com/go-gl/mathgl v1.0.0 // indirect
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/golang/geo v0.0.0-20210211234256-740aa86cb551 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	golang.org/x/exp v0.0.0-20210625193404-fa9d1d177d71 // indirect
+	golang.org/x/image v0.0.0-20210622092929-e6eecd499c2c // indirect
+	honnef.co/go/js/util v0.0.0-20150216223935-96b8dd9d1621 // indirect
+)
+
 // replace github.com/aubonbeurre/glplus => ../glplus
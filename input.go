@@ -24,13 +24,67 @@ type InputManager struct {
 	// Axis and Button system if at all possible.
 	Mouse Mouse
 
-	axes    map[string]Axis
-	buttons map[string]Button
-	keys    *KeyManager
+	// Touches holds the fingers on the screen, by pointer ID. A Touch which
+	// ended is kept during the frame it ended in, see TouchPhase.
+	Touches map[int64]Touch
+
+	axes     map[string]Axis
+	buttons  map[string]Button
+	keys     *KeyManager
+	gamepads [MaxGamepads]Gamepad
+
+	// sequences holds the progress of the Sequences, by Button name
+	sequences map[string][]sequenceState
+
+	// primaryTouch is the first finger put on the screen
+	primaryTouch int64
+
+	// mouseEvents are the mouse buttons pressed or released during the current frame
+	mouseEvents []MouseEvent
+
+	// text is typed during the current frame, composition is being composed
+	text        string
+	composition string
+
+	recorder *inputRecorder
+	replay   *inputReplay
+
+	// mouse and touches hold the actual state of the Mouse and the Touches
+	// while the input is blocked
+	mouse   Mouse
+	touches map[int64]Touch
+	blocked bool
 }
 
 func (im *InputManager) update() {
 	im.keys.update()
+	im.updateTouches()
+	im.text = ""
+	im.mouseEvents = nil
+}
+
+// setBlocked hides (or shows again) the keys, the gamepads, the touches, the
+// mouse buttons and the scrolling from the Systems, i.e. for the Scenes below
+// an Overlay. The mouse position remains visible.
+func (im *InputManager) setBlocked(blocked bool) {
+	if blocked == im.blocked {
+		return
+	}
+	im.blocked = blocked
+	im.keys.setBlocked(blocked)
+	for i := range im.gamepads {
+		im.gamepads[i].blocked = blocked
+	}
+
+	if blocked {
+		im.mouse = im.Mouse
+		im.Mouse.Action = Neutral
+		im.Mouse.ScrollX, im.Mouse.ScrollY = 0, 0
+		im.touches, im.Touches = im.Touches, nil
+	} else {
+		im.Mouse = im.mouse
+		im.Touches = im.touches
+	}
 }
 
 // RegisterAxis registers a new axis which can be used to retrieve inputs which are spectrums.
@@ -49,6 +103,28 @@ func (im *InputManager) RegisterButton(name string, keys ...Key) {
 	}
 }
 
+// RegisterChord adds a Chord to the triggers of the named Button, registering the Button if needed:
+//
+//    minieng.Input.RegisterChord("save", minieng.Control, minieng.S)
+func (im *InputManager) RegisterChord(name string, mods Modifier, keys ...Key) {
+	button := im.buttons[name]
+	button.Name = name
+	button.Chords = append(button.Chords, Chord{Modifiers: mods, Keys: keys})
+	im.buttons[name] = button
+}
+
+// RegisterSequence adds a Sequence to the triggers of the named Button, registering the Button if needed. A timeout
+// of 0 stands for SequenceTimeout:
+//
+//    minieng.Input.RegisterSequence("dash", 0.3, minieng.Chord{Keys: []minieng.Key{minieng.D}},
+//        minieng.Chord{Keys: []minieng.Key{minieng.D}})
+func (im *InputManager) RegisterSequence(name string, timeout float32, steps ...Chord) {
+	button := im.buttons[name]
+	button.Name = name
+	button.Sequences = append(button.Sequences, Sequence{Steps: steps, Timeout: timeout})
+	im.buttons[name] = button
+}
+
 // Axis retrieves an Axis with a specified name.
 func (im *InputManager) Axis(name string) Axis {
 	return im.axes[name]
@@ -1,5 +1,11 @@
 package minieng
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // Action corresponds to a control action such as move, press, release
 type Action int
 
@@ -33,6 +39,23 @@ var (
 	Super = Modifier(0x0008)
 )
 
+// modifierNames are the names of the modifiers, in the order of their bits
+var modifierNames = []string{"Shift", "Control", "Alt", "Super"}
+
+// String returns the names of the modifiers, i.e. "Shift+Control".
+func (m Modifier) String() string {
+	var names []string
+	for i, name := range modifierNames {
+		if m&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, "+")
+}
+
 // MouseButton corresponds to a mouse button.
 type MouseButton int
 
@@ -56,221 +79,290 @@ const (
 	MouseButtonLast MouseButton = 7
 )
 
-// those are default values for engo_js defined here because some of them are shared
-// with engo_glfw.
-// engo_glfw redefines the variables it needs to other values during init() so
-var (
+// Keys are the same on every backend, each backend maps its own key codes to them.
+const (
 	// Grave represents the '`' keyboard key
-	Grave Key = 192
+	Grave Key = iota + 1
 	// Dash represents the '-' keyboard key
-	Dash Key = 189
+	Dash
 	// Apostrophe represents the `'` keyboard key
-	Apostrophe Key = 222
+	Apostrophe
 	// Semicolon represents the ';' keyboard key
-	Semicolon Key = 186
+	Semicolon
 	// Equals reprsents the '=' keyboard key
-	Equals Key = 187
+	Equals
 	// Comma represents the ',' keyboard key
-	Comma Key = 188
+	Comma
 	// Period represents the '.' keyboard key
-	Period Key = 190
+	Period
 	// Slash represents the '/' keyboard key
-	Slash Key = 191
+	Slash
 	// Backslash represents the '\' keyboard key
-	Backslash Key = 220
+	Backslash
 	//Backspace represents the backspace keyboard key
-	Backspace Key = 8
+	Backspace
 	// Tab represents the tab keyboard key
-	Tab Key = 9
+	Tab
 	// CapsLock represents the caps lock keyboard key
-	CapsLock Key = 20
+	CapsLock
 	// Space represents the space keyboard key
-	Space Key = 32
+	Space
 	// Enter represents the enter keyboard key
-	Enter Key = 13
+	Enter
 	// Escape represents the escape keyboard key
-	Escape Key = 27
+	Escape
 	// Insert represents the insert keyboard key
-	Insert Key = 45
+	Insert
 	// PrintScreen represents the print screen keyboard key often
 	// represented by 'Prt Scrn', 'Prt Scn', or 'Print Screen'
-	PrintScreen Key = 42
+	PrintScreen
 	// Delete represents the delete keyboard key
-	Delete Key = 46
+	Delete
 	// PageUp represents the page up keyboard key
-	PageUp Key = 33
+	PageUp
 	// PageDown represents the page down keyboard key
-	PageDown Key = 34
+	PageDown
 	// Home represents the home keyboard key
-	Home Key = 36
+	Home
 	// End represents the end keyboard key
-	End Key = 35
+	End
 	// Pause represents the pause keyboard key
-	Pause Key = 19
+	Pause
 	// ScrollLock represents the scroll lock keyboard key
-	ScrollLock Key = 145
+	ScrollLock
 	// AllowLeft represents the arrow left keyboard key
-	ArrowLeft Key = 37
+	ArrowLeft
 	// ArrowRight represents the arrow right keyboard key
-	ArrowRight Key = 39
+	ArrowRight
 	// ArrowDown represents the down arrow keyboard key
-	ArrowDown Key = 40
+	ArrowDown
 	// ArrowUp represents the up arrow keyboard key
-	ArrowUp Key = 38
+	ArrowUp
 	// LeftBracket represents the '[' keyboard key
-	LeftBracket Key = 219
+	LeftBracket
 	// LeftShift represents the left shift keyboard key
-	LeftShift Key = 16
+	LeftShift
 	// LeftControl represents the left control keyboard key
-	LeftControl Key = 17
+	LeftControl
 	// LeftSuper represents the left super keyboard key
 	// (Windows key on Microsoft Windows, Command key on Apple OSX, and varies on Linux)
-	LeftSuper Key = 73
+	LeftSuper
 	// LeftAlt represents the left alt keyboard key
-	LeftAlt Key = 18
+	LeftAlt
 	// RightBracket represents the ']' keyboard key
-	RightBracket Key = 221
+	RightBracket
 	// RightShift represents the right shift keyboard key
-	RightShift Key = 16
+	RightShift
 	// RightControl represents the right control keyboard key
-	RightControl Key = 17
+	RightControl
 	// RightSuper represents the right super keyboard key
 	// (Windows key on Microsoft Windows, Command key on Apple OSX, and varies on Linux)
-	RightSuper Key = 73
+	RightSuper
 	// RightAlt represents the left alt keyboard key
-	RightAlt Key = 18
+	RightAlt
 	// Zero represents the '0' keyboard key
-	Zero Key = 48
+	Zero
 	// One represents the '1' keyboard key
-	One Key = 49
+	One
 	// Two represents the '2' keyboard key
-	Two Key = 50
+	Two
 	// Three represents the '3' keyboard key
-	Three Key = 51
+	Three
 	// Four represents the '4' keyboard key
-	Four Key = 52
+	Four
 	// Five represents the '5' keyboard key
-	Five Key = 53
+	Five
 	// Six represents the '6' keyboard key
-	Six Key = 54
+	Six
 	// Seven represents the '7' keyboard key
-	Seven Key = 55
+	Seven
 	// Eight represents the '8' keyboard key
-	Eight Key = 56
+	Eight
 	// Nine represents the  '9' keyboard key
-	Nine Key = 57
+	Nine
 	// F1 represents the 'F1' keyboard key
-	F1 Key = 112
+	F1
 	// F2 represents the 'F2' keyboard key
-	F2 Key = 113
+	F2
 	// F3 represents the 'F3' keyboard key
-	F3 Key = 114
+	F3
 	// F4 represents the 'F4' keyboard key
-	F4 Key = 115
+	F4
 	// F5 represents the 'F5' keyboard key
-	F5 Key = 116
+	F5
 	// F6 represents the 'F6' keyboard key
-	F6 Key = 117
+	F6
 	// F7 represents the 'F7' keyboard key
-	F7 Key = 118
+	F7
 	// F8 represents the 'F8' keyboard key
-	F8 Key = 119
+	F8
 	// F9 represents the 'F9' keyboard key
-	F9 Key = 120
+	F9
 	// F10 represents the 'F10' keyboard key
-	F10 Key = 121
+	F10
 	// F11 represents the 'F11' keyboard key
-	F11 Key = 122
+	F11
 	// F12 represents the 'F12' keyboard key
-	F12 Key = 123
+	F12
 	// A represents the 'A' keyboard key
-	A Key = 65
+	A
 	// B represents the 'B' keyboard key
-	B Key = 66
+	B
 	// C represents the 'C' keyboard key
-	C Key = 67
+	C
 	// D represents the 'D' keyboard key '
-	D Key = 68
+	D
 	// E represents the 'E' keyboard key
-	E Key = 69
+	E
 	// F represents the 'F' keyboard key
-	F Key = 70
+	F
 	// G represents the 'G' keyboard key
-	G Key = 71
+	G
 	// H represents the 'H' keyboard key
-	H Key = 72
+	H
 	// I represents the 'I' keyboard key
-	I Key = 73
+	I
 	// J represents the 'J' keyboard key
-	J Key = 74
+	J
 	// K represents the 'K' keyboard key
-	K Key = 75
+	K
 	// L represents the 'L' keyboard key
-	L Key = 76
+	L
 	// M represents the 'M' keyboard key
-	M Key = 77
+	M
 	// N represents the 'N' keyboard key
-	N Key = 78
+	N
 	// O represents the 'O' keyboard key
-	O Key = 79
+	O
 	// P represents the 'P' keyboard key
-	P Key = 80
+	P
 	// Q represents the 'Q' keyboard key
-	Q Key = 81
+	Q
 	// R represents the 'R' keyboard key
-	R Key = 82
+	R
 	// S represents the 'S' keyboard key
-	S Key = 83
+	S
 	// T represents the 'T' keyboard key
-	T Key = 84
+	T
 	// U represents the 'U' keyboard key
-	U Key = 85
+	U
 	// V represents the 'V' keyboard key
-	V Key = 86
+	V
 	// W represents the 'W' keyboard key
-	W Key = 87
+	W
 	// X represents the 'X' keyboard key
-	X Key = 88
+	X
 	// Y represents the 'Y' keyboard key
-	Y Key = 89
+	Y
 	// Z represents the 'Z' keyboard key
-	Z Key = 90
+	Z
 	// NumLock represents the NumLock keyboard key on the numpad
-	NumLock Key = 144
+	NumLock
 	// NumMultiply represents the NumMultiply keyboard key on the numpad
-	NumMultiply Key = 106
+	NumMultiply
 	// NumDivide represents the NumDivide keyboard key on the numpad
-	NumDivide Key = 111
+	NumDivide
 	// NumAdd represents the NumAdd keyboard key on the numpad
-	NumAdd Key = 107
+	NumAdd
 	// NumSubtract represents the NumSubtract keyboard key on the numpad
-	NumSubtract Key = 109
+	NumSubtract
 	// NumZero represents the NumZero keyboard key on the numpad
-	NumZero Key = 96
+	NumZero
 	// NumOne represents the NumOne keyboard key on the numpad
-	NumOne Key = 97
+	NumOne
 	// NumTwo represents the NumTwo keyboard key on the numpad
-	NumTwo Key = 98
+	NumTwo
 	// NumThree represents the NumThree keyboard key on the numpad
-	NumThree Key = 99
+	NumThree
 	// NumFour represents the NumFour keyboard key on the numpad
-	NumFour Key = 100
+	NumFour
 	// NumFive represents the NumFive keyboard key on the numpad
-	NumFive Key = 101
+	NumFive
 	// NumSiz represents the NumSix keyboard key on the numpad
-	NumSix Key = 102
+	NumSix
 	// NumSeven represents the NumSeven keyboard key on the numpad
-	NumSeven Key = 103
+	NumSeven
 	// NumEight represents the NumEight keyboard key on the numpad
-	NumEight Key = 104
+	NumEight
 	// NumNine represents the NumNine keyboard key on the numpad
-	NumNine Key = 105
+	NumNine
 	// NumDecimal represents the NumDecimal keyboard key on the numpad
-	NumDecimal Key = 110
+	NumDecimal
 	// NumEnter represents the NumEnter keyboard key on the numpad
-	NumEnter Key = 13
+	NumEnter
+	// NumEqual represents the NumEqual keyboard key on the numpad
+	NumEqual
+
+	keyLast
 )
 
+// keyNames are the names of the keys, as returned by Key.String
+var keyNames = [keyLast]string{
+	Grave: "Grave", Dash: "Dash", Apostrophe: "Apostrophe", Semicolon: "Semicolon", Equals: "Equals",
+	Comma: "Comma", Period: "Period", Slash: "Slash", Backslash: "Backslash", Backspace: "Backspace", Tab: "Tab",
+	CapsLock: "CapsLock", Space: "Space", Enter: "Enter", Escape: "Escape", Insert: "Insert",
+	PrintScreen: "PrintScreen", Delete: "Delete", PageUp: "PageUp", PageDown: "PageDown", Home: "Home",
+	End: "End", Pause: "Pause", ScrollLock: "ScrollLock", ArrowLeft: "ArrowLeft", ArrowRight: "ArrowRight",
+	ArrowDown: "ArrowDown", ArrowUp: "ArrowUp", LeftBracket: "LeftBracket", LeftShift: "LeftShift",
+	LeftControl: "LeftControl", LeftSuper: "LeftSuper", LeftAlt: "LeftAlt", RightBracket: "RightBracket",
+	RightShift: "RightShift", RightControl: "RightControl", RightSuper: "RightSuper", RightAlt: "RightAlt",
+	Zero: "Zero", One: "One", Two: "Two", Three: "Three", Four: "Four", Five: "Five", Six: "Six", Seven: "Seven",
+	Eight: "Eight", Nine: "Nine", F1: "F1", F2: "F2", F3: "F3", F4: "F4", F5: "F5", F6: "F6", F7: "F7", F8: "F8",
+	F9: "F9", F10: "F10", F11: "F11", F12: "F12", A: "A", B: "B", C: "C", D: "D", E: "E", F: "F", G: "G", H: "H",
+	I: "I", J: "J", K: "K", L: "L", M: "M", N: "N", O: "O", P: "P", Q: "Q", R: "R", S: "S", T: "T", U: "U",
+	V: "V", W: "W", X: "X", Y: "Y", Z: "Z", NumLock: "NumLock", NumMultiply: "NumMultiply",
+	NumDivide: "NumDivide", NumAdd: "NumAdd", NumSubtract: "NumSubtract", NumZero: "NumZero", NumOne: "NumOne",
+	NumTwo: "NumTwo", NumThree: "NumThree", NumFour: "NumFour", NumFive: "NumFive", NumSix: "NumSix",
+	NumSeven: "NumSeven", NumEight: "NumEight", NumNine: "NumNine", NumDecimal: "NumDecimal",
+	NumEnter: "NumEnter", NumEqual: "NumEqual",
+}
+
+// String returns the name of the key, i.e. "Space", "ArrowLeft", "MouseLeft" for the mouse buttons or "Gamepad0.A"
+// for the gamepad buttons. The names are the same on every backend.
+func (k Key) String() string {
+	if k >= gamepadKeyBase {
+		i := int(k - gamepadKeyBase)
+		return fmt.Sprintf("Gamepad%d.%s", i/gamepadButtonCount, GamepadButton(i%gamepadButtonCount))
+	}
+	if k >= mouseKeyBase && k <= MouseKey(MouseButtonLast) {
+		return "Mouse" + MouseButton(k-mouseKeyBase).String()
+	}
+	if k > 0 && k < keyLast {
+		return keyNames[k]
+	}
+	return fmt.Sprintf("Key(%d)", int(k))
+}
+
+// ParseKey returns the key with the given name, see Key.String.
+func ParseKey(name string) (Key, error) {
+	if strings.HasPrefix(name, "Gamepad") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "Gamepad"), ".", 2)
+		id, err := strconv.Atoi(parts[0])
+		if err == nil && id >= 0 && id < MaxGamepads && len(parts) == 2 {
+			for button := GamepadButton(0); button < gamepadButtonCount; button++ {
+				if button.String() == parts[1] {
+					return GamepadKey(id, button), nil
+				}
+			}
+		}
+	}
+
+	if strings.HasPrefix(name, "Mouse") {
+		for b := MouseButtonLeft; b <= MouseButtonLast; b++ {
+			if "Mouse"+b.String() == name {
+				return MouseKey(b), nil
+			}
+		}
+	}
+
+	for k, keyName := range keyNames {
+		if keyName == name && k > 0 {
+			return Key(k), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
 // MouseState represents the current state of the Mouse (or latest Touch-events).
 type MouseState struct {
 	// X and Y are the coordinates of the Mouse, relative to the `Canvas`.
@@ -28,17 +28,103 @@ type KeyManager struct {
 	dirtmap map[Key]Key
 	mapper  map[Key]KeyState
 	mutex   sync.RWMutex
+
+	// blocked makes every key look released
+	blocked bool
+
+	// record receives every call to Set, which is ignored while replaying
+	record    func(k Key, state bool)
+	replaying bool
+
+	// listen receives the next key pressed, see InputManager.ListenNextKey
+	listen func(k Key)
+	// swallowed are the keys given to listen, whose release is swallowed as well
+	swallowed map[Key]bool
+
+	// pending are the changes postponed to the next frame
+	pending []keyEvent
 }
 
 // Set is used for updating whether or not a key is held down, or not held down.
 func (km *KeyManager) Set(k Key, state bool) {
 	km.mutex.Lock()
+	if km.replaying {
+		km.mutex.Unlock()
+		return
+	}
+	if listen := km.listen; listen != nil && state {
+		km.listen = nil
+		if km.swallowed == nil {
+			km.swallowed = make(map[Key]bool)
+		}
+		km.swallowed[k] = true
+		km.mutex.Unlock()
+		listen(k)
+		return
+	}
+	if km.swallowed[k] {
+		// the key is held since it was given to listen, i.e. repeated
+		if !state {
+			delete(km.swallowed, k)
+		}
+		km.mutex.Unlock()
+		return
+	}
+	record := km.record
+	km.mutex.Unlock()
+
+	km.set(k, state)
+	if record != nil {
+		record(k, state)
+	}
+}
+
+// set updates the state of a key, it is also used to replay the input.
+func (km *KeyManager) set(k Key, state bool) {
+	km.mutex.Lock()
+	km.apply(k, state)
+	km.mutex.Unlock()
+}
+
+// apply updates the state of a key. A key which already changed during the current frame changes again during the
+// next one, so that a press and a release within a single frame are both seen.
+func (km *KeyManager) apply(k Key, state bool) {
+	if _, dirty := km.dirtmap[k]; dirty || km.postponed(k) {
+		km.pending = append(km.pending, keyEvent{Key: k, Down: state})
+		return
+	}
 
 	ks := km.mapper[k]
 	ks.set(state)
 	km.mapper[k] = ks
 	km.dirtmap[k] = k
+}
 
+// postponed returns whether a change of the key is pending.
+func (km *KeyManager) postponed(k Key) bool {
+	for _, e := range km.pending {
+		if e.Key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (km *KeyManager) setRecord(record func(k Key, state bool)) {
+	km.mutex.Lock()
+	km.record = record
+	km.mutex.Unlock()
+}
+
+func (km *KeyManager) setListen(listen func(k Key)) {
+	km.mutex.Lock()
+	km.listen = listen
+	km.mutex.Unlock()
+}
+
+func (km *KeyManager) setReplaying(replaying bool) {
+	km.mutex.Lock()
+	km.replaying = replaying
 	km.mutex.Unlock()
 }
 
@@ -46,11 +132,49 @@ func (km *KeyManager) Set(k Key, state bool) {
 func (km *KeyManager) Get(k Key) KeyState {
 	km.mutex.RLock()
 	ks := km.mapper[k]
+	if km.blocked {
+		ks = KeyState{}
+	}
 	km.mutex.RUnlock()
 
 	return ks
 }
 
+// modifiers returns the modifiers held now, or during the previous frame.
+func (km *KeyManager) modifiers(previous bool) Modifier {
+	var mods Modifier
+	for k, mod := range modifierKeys {
+		ks := km.Get(k)
+		if previous && ks.lastState || !previous && ks.currentState {
+			mods |= mod
+		}
+	}
+	return mods
+}
+
+// justPressed returns the keys pressed during the current frame.
+func (km *KeyManager) justPressed() []Key {
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	if km.blocked {
+		return nil
+	}
+	var keys []Key
+	for _, k := range km.dirtmap {
+		if km.mapper[k].JustPressed() {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (km *KeyManager) setBlocked(blocked bool) {
+	km.mutex.Lock()
+	km.blocked = blocked
+	km.mutex.Unlock()
+}
+
 func (km *KeyManager) update() {
 	km.mutex.Lock()
 
@@ -63,6 +187,12 @@ func (km *KeyManager) update() {
 		km.mapper[key] = state
 	}
 
+	pending := km.pending
+	km.pending = nil
+	for _, e := range pending {
+		km.apply(e.Key, e.Down)
+	}
+
 	km.mutex.Unlock()
 }
 
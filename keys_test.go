@@ -0,0 +1,53 @@
+//+build headless
+
+package minieng
+
+import "testing"
+
+func TestParseKey(t *testing.T) {
+	var keys []Key
+	for k, name := range keyNames {
+		if name != "" {
+			keys = append(keys, Key(k))
+		}
+	}
+	for b := MouseButtonLeft; b <= MouseButtonLast; b++ {
+		keys = append(keys, MouseKey(b))
+	}
+	keys = append(keys, GamepadKey(0, GamepadA), GamepadKey(MaxGamepads-1, GamepadDpadLeft))
+
+	for _, k := range keys {
+		parsed, err := ParseKey(k.String())
+		if err != nil {
+			t.Errorf("ParseKey(%q): %v", k.String(), err)
+		} else if parsed != k {
+			t.Errorf("ParseKey(%q) = %v, want %v", k.String(), parsed, k)
+		}
+	}
+
+	for _, name := range []string{"", "Key(0)", "Gamepad16.A", "Gamepad0.Z", "MouseNone", "space"} {
+		if k, err := ParseKey(name); err == nil {
+			t.Errorf("ParseKey(%q) = %v, want an error", name, k)
+		}
+	}
+}
+
+func TestKeyPressedAndReleasedWithinFrame(t *testing.T) {
+	km := NewKeyManager()
+
+	km.Set(Space, true)
+	km.Set(Space, false)
+	if !km.Get(Space).JustPressed() {
+		t.Error("the press was not seen during the first frame")
+	}
+
+	km.update()
+	if !km.Get(Space).JustReleased() {
+		t.Error("the release was not seen during the second frame")
+	}
+
+	km.update()
+	if ks := km.Get(Space); ks.State() != KeyStateUp {
+		t.Errorf("state during the third frame = %d, want KeyStateUp", ks.State())
+	}
+}
@@ -1,8 +1,18 @@
 package minieng
 
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
 //A MessageHandler is used to dispatch a message to the subscribed handler.
 type MessageHandler func(msg Message)
 
+// A ConsumingHandler is a MessageHandler which can stop the propagation of the message to the handlers with a
+// lower priority, by returning true.
+type ConsumingHandler func(msg Message) bool
+
 // A Message is used to send messages within the MessageManager
 type Message interface {
 	Type() string
@@ -10,27 +20,200 @@ type Message interface {
 
 // MessageManager manages messages and subscribed handlers
 type MessageManager struct {
-	listeners map[string][]MessageHandler
+	listeners map[string][]*listener
+
+	// queue holds the messages posted since the last flush
+	queue     []postedMessage
+	queueLock sync.Mutex
+
+	tracer *MessageTracer
 }
 
-// Dispatch sends a message to all subscribed handlers of the message's type
+// postedMessage is a message queued by Post, with the frame it was posted during
+type postedMessage struct {
+	message Message
+	frame   uint64
+}
+
+// listener is a handler subscribed to a type of message
+type listener struct {
+	priority  int
+	handler   ConsumingHandler
+	cancelled bool
+}
+
+// Subscription is returned when subscribing to a type of message, and allows to stop listening.
+type Subscription struct {
+	mm          *MessageManager
+	messageType string
+	listener    *listener
+}
+
+// Cancel unsubscribes the handler, it won't be called anymore; even for a message being dispatched.
+func (s *Subscription) Cancel() {
+	if s.listener.cancelled {
+		return
+	}
+	s.listener.cancelled = true
+
+	listeners := s.mm.listeners[s.messageType]
+	for i, l := range listeners {
+		if l == s.listener {
+			// don't modify the slice in place, it may be dispatching
+			remaining := make([]*listener, 0, len(listeners)-1)
+			remaining = append(remaining, listeners[:i]...)
+			s.mm.listeners[s.messageType] = append(remaining, listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// Dispatch sends a message to all subscribed handlers of the message's type, by order of priority
 func (mm *MessageManager) Dispatch(message Message) {
+	mm.dispatch(message, currentFrame(), false)
+}
+
+// dispatch sends the message sent during the given frame, and traces it.
+func (mm *MessageManager) dispatch(message Message, frame uint64, posted bool) {
 	handlers := mm.listeners[message.Type()]
 
-	for _, handler := range handlers {
-		handler(message)
+	called, consumed := 0, false
+	for _, l := range handlers {
+		if l.cancelled {
+			continue
+		}
+		called++
+		if l.handler(message) {
+			consumed = true
+			break
+		}
+	}
+
+	trace := MessageTrace{
+		Type:     message.Type(),
+		Frame:    frame,
+		Handlers: called,
+		Consumed: consumed,
+		Posted:   posted,
+	}
+	if mm.tracer != nil {
+		mm.tracer.trace(trace)
+	}
+	if tracer := engineTracer(); tracer != nil && tracer != mm.tracer {
+		tracer.trace(trace)
+	}
+}
+
+// SetTracer records every message dispatched by the MessageManager into the given MessageTracer, which may be
+// shared between several MessageManagers. A nil tracer stops the recording. See SetMessageTracer to trace the
+// MessageManagers of every Scene.
+func (mm *MessageManager) SetTracer(tracer *MessageTracer) {
+	mm.tracer = tracer
+}
+
+// MaxQueuedMessages is the number of messages a MessageManager keeps queued by Post, the oldest ones being dropped
+// beyond; there is no limit when it is 0 or less. It only matters for the Scenes which are not in the stack of
+// Scenes, the others being flushed every frame.
+var MaxQueuedMessages = 1024
+
+// Post queues a message, to be dispatched during the next frame, before the Systems of the Scene are updated. It
+// is safe to call from any goroutine, or from a handler: messages posted while the queue is being flushed are
+// dispatched the frame after. The messages posted to a Scene which is not in the stack wait until it is pushed
+// again, see MaxQueuedMessages.
+func (mm *MessageManager) Post(message Message) {
+	posted := postedMessage{message: message, frame: currentFrame()}
+
+	mm.queueLock.Lock()
+	if limit := MaxQueuedMessages; limit > 0 && len(mm.queue) >= limit {
+		mm.queue = append(mm.queue[:0:0], mm.queue[len(mm.queue)-limit+1:]...)
+	}
+	mm.queue = append(mm.queue, posted)
+	mm.queueLock.Unlock()
+}
+
+// Flush dispatches the messages queued by Post, in order. It is invoked by the engine once every frame.
+func (mm *MessageManager) Flush() {
+	mm.queueLock.Lock()
+	queue := mm.queue
+	mm.queue = nil
+	mm.queueLock.Unlock()
+
+	for _, posted := range queue {
+		mm.dispatch(posted.message, posted.frame, true)
 	}
 }
 
 // Listen subscribes to the specified message type and calls the specified handler when fired
-func (mm *MessageManager) Listen(messageType string, handler MessageHandler) {
+func (mm *MessageManager) Listen(messageType string, handler MessageHandler) *Subscription {
+	return mm.ListenWithPriority(messageType, 0, func(msg Message) bool {
+		handler(msg)
+		return false
+	})
+}
+
+// ListenWithPriority subscribes to the specified message type like Listen does, except the handlers with a higher
+// priority are called first, and the handler can stop the propagation of the message by returning true. Handlers
+// with the same priority are called in the order they subscribed. Listen uses the priority 0.
+func (mm *MessageManager) ListenWithPriority(messageType string, priority int, handler ConsumingHandler) *Subscription {
 	if mm.listeners == nil {
-		mm.listeners = make(map[string][]MessageHandler)
+		mm.listeners = make(map[string][]*listener)
 	}
-	mm.listeners[messageType] = append(mm.listeners[messageType], handler)
+
+	l := &listener{priority: priority, handler: handler}
+
+	listeners := mm.listeners[messageType]
+	i := sort.Search(len(listeners), func(i int) bool {
+		return listeners[i].priority < priority
+	})
+
+	// don't modify the slice in place, it may be dispatching
+	inserted := make([]*listener, 0, len(listeners)+1)
+	inserted = append(inserted, listeners[:i]...)
+	inserted = append(inserted, l)
+	mm.listeners[messageType] = append(inserted, listeners[i:]...)
+
+	return &Subscription{mm: mm, messageType: messageType, listener: l}
 }
 
-// WindowResizeMessage is a message that's being dispatched whenever the game window is being resized by the gamer
+// Subscribe subscribes to the messages of type T, and calls the handler with them without the need of a type
+// assertion:
+//
+//    minieng.Subscribe(minieng.Mailbox, func(msg minieng.WindowResizeMessage) {
+//        ...
+//    })
+//
+// Messages dispatched with the same Type() but another Go type, i.e. a pointer instead of a value, are ignored.
+func Subscribe[T Message](mm *MessageManager, handler func(msg T)) *Subscription {
+	return SubscribeWithPriority(mm, 0, func(msg T) bool {
+		handler(msg)
+		return false
+	})
+}
+
+// SubscribeWithPriority is the typed version of ListenWithPriority, see Subscribe.
+func SubscribeWithPriority[T Message](mm *MessageManager, priority int, handler func(msg T) bool) *Subscription {
+	return mm.ListenWithPriority(messageType[T](), priority, func(msg Message) bool {
+		if typed, ok := msg.(T); ok {
+			return handler(typed)
+		}
+		return false
+	})
+}
+
+// messageType returns what Type() returns for the messages of type T
+func messageType[T Message]() string {
+	var zero T
+
+	// calling Type() on a nil pointer would panic if it has a value receiver
+	if t := reflect.TypeOf(zero); t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(Message).Type()
+	}
+	return zero.Type()
+}
+
+// WindowResizeMessage is a message that's being dispatched whenever the game window is being resized by the gamer.
+// It is posted to EngineMailbox, and forwarded to the Mailbox of every Scene in the stack; the other Scenes receive
+// the resizes they missed when they are shown again.
 type WindowResizeMessage struct {
 	OldWidth, OldHeight int
 	NewWidth, NewHeight int
@@ -38,3 +221,74 @@ type WindowResizeMessage struct {
 
 // Type returns the type of the current object "WindowResizeMessage"
 func (WindowResizeMessage) Type() string { return "WindowResizeMessage" }
+
+// WindowFocusMessage is a message that's being dispatched whenever the game window gains or loses the focus. It
+// is posted to EngineMailbox.
+type WindowFocusMessage struct {
+	Focused bool
+}
+
+// Type returns the type of the current object "WindowFocusMessage"
+func (WindowFocusMessage) Type() string { return "WindowFocusMessage" }
+
+// WindowIconifyMessage is a message that's being dispatched whenever the game window is minimized or restored. It
+// is posted to EngineMailbox.
+type WindowIconifyMessage struct {
+	Iconified bool
+}
+
+// Type returns the type of the current object "WindowIconifyMessage"
+func (WindowIconifyMessage) Type() string { return "WindowIconifyMessage" }
+
+// WindowContentScaleMessage is a message that's being dispatched whenever the ratio between the canvas and the
+// window size changes, i.e. when the window is moved to a screen with another DPI. CanvasScale() already returns
+// NewScale when it is received. It is posted to EngineMailbox.
+type WindowContentScaleMessage struct {
+	OldScale, NewScale float32
+}
+
+// Type returns the type of the current object "WindowContentScaleMessage"
+func (WindowContentScaleMessage) Type() string { return "WindowContentScaleMessage" }
+
+// WindowCloseRequestMessage is a message that's being dispatched on EngineMailbox whenever the user or the system
+// requests to close the game. It is dispatched right away, so that a handler can call Cancel to keep the game
+// running, i.e. to ask for confirmation first:
+//
+//    minieng.Subscribe(minieng.EngineMailbox, func(msg *minieng.WindowCloseRequestMessage) {
+//        if unsaved {
+//            msg.Cancel()
+//        }
+//    })
+type WindowCloseRequestMessage struct {
+	cancelled bool
+}
+
+// Type returns the type of the current object "WindowCloseRequestMessage"
+func (*WindowCloseRequestMessage) Type() string { return "WindowCloseRequestMessage" }
+
+// Cancel prevents the game from closing.
+func (m *WindowCloseRequestMessage) Cancel() {
+	m.cancelled = true
+}
+
+// Cancelled returns whether a handler called Cancel.
+func (m *WindowCloseRequestMessage) Cancelled() bool {
+	return m.cancelled
+}
+
+// EntityAddedMessage is a message that's being dispatched whenever an entity has been added through `World.AddEntity`
+type EntityAddedMessage struct {
+	Entity BasicEntity
+}
+
+// Type returns the type of the current object "EntityAddedMessage"
+func (EntityAddedMessage) Type() string { return "EntityAddedMessage" }
+
+// EntityRemovedMessage is a message that's being dispatched whenever an entity has been removed through
+// `World.RemoveEntity`
+type EntityRemovedMessage struct {
+	Entity BasicEntity
+}
+
+// Type returns the type of the current object "EntityRemovedMessage"
+func (EntityRemovedMessage) Type() string { return "EntityRemovedMessage" }
@@ -0,0 +1,131 @@
+//+build headless
+
+package minieng
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testMessage struct {
+	Text string
+}
+
+func (testMessage) Type() string { return "testMessage" }
+
+func TestSubscribeCancel(t *testing.T) {
+	mm := &MessageManager{}
+
+	var got []string
+	sub := Subscribe(mm, func(msg testMessage) {
+		got = append(got, msg.Text)
+	})
+
+	mm.Dispatch(testMessage{"a"})
+	sub.Cancel()
+	sub.Cancel()
+	mm.Dispatch(testMessage{"b"})
+
+	if strings.Join(got, ",") != "a" {
+		t.Errorf("received %v, want [a]", got)
+	}
+}
+
+func TestSubscribeWithPriority(t *testing.T) {
+	mm := &MessageManager{}
+
+	var got []string
+	listen := func(name string, priority int, consume bool) *Subscription {
+		return SubscribeWithPriority(mm, priority, func(msg testMessage) bool {
+			got = append(got, name)
+			return consume && msg.Text == "consume"
+		})
+	}
+	listen("low", -1, false)
+	listen("first", 0, false)
+	listen("second", 0, false)
+	listen("high", 10, true)
+
+	mm.Dispatch(testMessage{})
+	if got, want := strings.Join(got, ","), "high,first,second,low"; got != want {
+		t.Errorf("handlers called %s, want %s", got, want)
+	}
+
+	got = nil
+	mm.Dispatch(testMessage{"consume"})
+	if got, want := strings.Join(got, ","), "high"; got != want {
+		t.Errorf("handlers called %s after the message was consumed, want %s", got, want)
+	}
+}
+
+func TestCancelWhileDispatching(t *testing.T) {
+	mm := &MessageManager{}
+
+	var got []string
+	var second *Subscription
+	SubscribeWithPriority(mm, 1, func(testMessage) bool {
+		got = append(got, "first")
+		second.Cancel()
+		return false
+	})
+	second = Subscribe(mm, func(testMessage) {
+		got = append(got, "second")
+	})
+
+	mm.Dispatch(testMessage{})
+	if got, want := strings.Join(got, ","), "first"; got != want {
+		t.Errorf("handlers called %s, want %s", got, want)
+	}
+}
+
+func TestPostQueueLimit(t *testing.T) {
+	defer func(limit int) { MaxQueuedMessages = limit }(MaxQueuedMessages)
+
+	for _, test := range []struct {
+		limit int
+		want  string
+	}{
+		{limit: 2, want: "c,d"},
+		{limit: 0, want: "a,b,c,d"},
+		{limit: -1, want: "a,b,c,d"},
+	} {
+		MaxQueuedMessages = test.limit
+		mm := &MessageManager{}
+		var got []string
+		Subscribe(mm, func(msg testMessage) { got = append(got, msg.Text) })
+
+		for _, text := range []string{"a", "b", "c", "d"} {
+			mm.Post(testMessage{text})
+		}
+		mm.Flush()
+		if strings.Join(got, ",") != test.want {
+			t.Errorf("with MaxQueuedMessages = %d, received %v, want [%s]", test.limit, got, test.want)
+		}
+	}
+}
+
+func TestPostWhileTicking(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	mm := &MessageManager{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mm.Post(testMessage{})
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		Time.Tick()
+	}
+	wg.Wait()
+
+	if frame := currentFrame(); frame != Time.Frame() {
+		t.Errorf("currentFrame() = %d, want %d", frame, Time.Frame())
+	}
+}
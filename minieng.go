@@ -1,5 +1,7 @@
 package minieng
 
+import "sync/atomic"
+
 // RunOptions ...
 type RunOptions struct {
 	// Title is the Window title
@@ -7,32 +9,95 @@ type RunOptions struct {
 
 	// Width ...
 	Width, Height int
+
+	// FixedTimeStep enables the fixed-step simulation when > 0. It is the duration, in seconds, of a single call
+	// to `FixedUpdate` on Systems implementing `FixedUpdater`, regardless of the actual framerate.
+	FixedTimeStep float32
+
+	// TargetFPS is the framerate the main loop is throttled to. Defaults to 30 with GLFW. On the web, the frames
+	// follow requestAnimationFrame (the refresh rate of the display) unless it is set. The mobile backend always
+	// follows the display, and the headless one HeadlessFrameDuration.
+	TargetFPS int
+
+	// Unlimited disables throttling of the main loop: frames are rendered as fast as possible (or as fast as
+	// the display allows, when combined with VSync). It is ignored on the web and on mobile, where the display
+	// paces the frames.
+	Unlimited bool
+
+	// VSync synchronizes buffer swaps with the refresh rate of the display. Only GLFW needs it, the web and
+	// mobile backends are always synchronized.
+	VSync bool
+
+	// IdleFPS enables the idle mode when > 0: the main loop drops to this framerate whenever the window is
+	// unfocused, or no input arrived for IdleAfter seconds. Any input immediately restores TargetFPS. Only GLFW
+	// supports it, browsers and mobile systems throttle the pages and apps in the background themselves.
+	IdleFPS int
+
+	// IdleAfter is the amount of seconds without input after which the idle mode kicks in. Defaults to 2.
+	IdleAfter float32
+
+	// SceneEviction decides when Scenes which are no longer in use get discarded, see EvictionPolicy.
+	SceneEviction EvictionPolicy
+
+	// MaxCatchUpSteps limits the number of fixed steps run within a single frame, so a slow frame does not
+	// cascade into even slower ones. Defaults to 5.
+	MaxCatchUpSteps int
+
+	// OverrideCloseAction prevents the game from closing when the user or the system requests it: the Exiter
+	// Scenes are told about the request, and one of them has to call Exit to actually close the game.
+	OverrideCloseAction bool
+
+	// RecordInput is the path of a file the input of every frame gets recorded into, see InputManager.Record.
+	RecordInput string
+
+	// ReplayInput is the path of a file recorded with RecordInput, to replay it from the first frame, see
+	// InputManager.Replay.
+	ReplayInput string
 }
 
 // Exit is the safest way to close your game, as `engo` will correctly attempt to close all windows, handlers and contexts
 func Exit() {
-	closeGame = true
+	atomic.StoreInt32(&closeGame, 1)
+}
+
+// closing returns whether Exit has been called.
+func closing() bool {
+	return atomic.LoadInt32(&closeGame) != 0
 }
 
-// CloseEvent ...
+// CloseEvent is invoked when the user or the system requests to close the game. It dispatches a
+// WindowCloseRequestMessage on EngineMailbox, and unless a handler cancels it, calls Exit on the Scenes and closes
+// the game, or leaves it to them when OverrideCloseAction is set.
 func CloseEvent() {
+	request := &WindowCloseRequestMessage{}
+	EngineMailbox.Dispatch(request)
+	if request.Cancelled() {
+		return
+	}
+
 	for _, scenes := range scenes {
 		if exiter, ok := scenes.scene.(Exiter); ok {
 			exiter.Exit()
 		}
 	}
-	Exit()
+	if !opts.OverrideCloseAction {
+		Exit()
+	}
 }
 
 // Run is called to create a window, initialize everything, and start the main loop. Once this function returns,
 // the game window has been closed already. You can supply a lot of options within `RunOptions`, and your starting
 // `Scene` should be defined in `defaultScene`.
 func Run(o RunOptions, defaultScene Scene) {
+	opts = o
 
 	// Create input
 	Input = NewInputManager()
 	Files.SetRoot("assets")
 
+	startInputFiles(o)
+	defer Input.StopRecording()
+
 	CreateWindow(o.Title, o.Width, o.Height)
 	defer DestroyWindow()
 
@@ -1,4 +1,4 @@
-//+build !netgo,!android
+//+build !netgo,!android,!headless
 
 package minieng
 
@@ -45,10 +45,9 @@ var (
 	canvasWidth  float32
 	canvasHeight float32
 	retinaScale  float32 = 1
-)
 
-const (
-	targetFPS = 30
+	// lastInput is the time of the last input event, used by the idle mode
+	lastInput float64
 )
 
 // WindowWidth ...
@@ -85,13 +84,19 @@ func errorCallback(err glfw.ErrorCode, desc string) {
 // here we check for the escape key being pressed. if it is pressed,
 // request that the window be closed
 func keyCallback(w *glfw.Window, k glfw.Key, scancode int, a glfw.Action, mods glfw.ModifierKey) {
+	lastInput = glfw.GetTime()
+
 	platform.keyChange(w, k, scancode, a, mods)
 
-	if platform.imguiIO.WantCaptureKeyboard() {
+	// only the presses go to imgui, the key may have been pressed before it captured the keyboard
+	if a == glfw.Press && platform.imguiIO.WantCaptureKeyboard() {
 		return
 	}
 
-	key := Key(k)
+	key, ok := glfwKeys[k]
+	if !ok {
+		return
+	}
 	if a == glfw.Press {
 		Input.keys.Set(key, true)
 	} else if a == glfw.Release {
@@ -100,9 +105,12 @@ func keyCallback(w *glfw.Window, k glfw.Key, scancode int, a glfw.Action, mods g
 }
 
 func mouseDownCallback(w *glfw.Window, b glfw.MouseButton, a glfw.Action, m glfw.ModifierKey) {
+	lastInput = glfw.GetTime()
+
 	platform.mouseButtonChange(w, b, a, m)
 
-	if platform.imguiIO.WantCaptureMouse() {
+	// only the presses go to imgui, the button may have been pressed before it captured the mouse
+	if a == glfw.Press && platform.imguiIO.WantCaptureMouse() {
 		return
 	}
 
@@ -111,17 +119,16 @@ func mouseDownCallback(w *glfw.Window, b glfw.MouseButton, a glfw.Action, m glfw
 
 	// this is only valid because we use an internal structure that is
 	// 100% compatible with glfw3.h
-	Input.Mouse.Button = MouseButton(b)
-	Input.Mouse.Modifer = Modifier(m)
-
 	if a == glfw.Press {
-		Input.Mouse.Action = Press
+		Input.mouseButton(MouseButton(b), Press, Modifier(m))
 	} else {
-		Input.Mouse.Action = Release
+		Input.mouseButton(MouseButton(b), Release, Modifier(m))
 	}
 }
 
 func mouseMoveCallback(w *glfw.Window, x float64, y float64) {
+	lastInput = glfw.GetTime()
+
 	if platform.imguiIO.WantCaptureMouse() {
 		return
 	}
@@ -133,6 +140,8 @@ func mouseMoveCallback(w *glfw.Window, x float64, y float64) {
 }
 
 func mouseWheelCallback(w *glfw.Window, xoff float64, yoff float64) {
+	lastInput = glfw.GetTime()
+
 	platform.mouseScrollChange(w, xoff, yoff)
 
 	if platform.imguiIO.WantCaptureMouse() {
@@ -144,6 +153,8 @@ func mouseWheelCallback(w *glfw.Window, xoff float64, yoff float64) {
 }
 
 func onSizeCallback(w *glfw.Window, width int, height int) {
+	lastInput = glfw.GetTime()
+
 	message := WindowResizeMessage{
 		OldWidth:  int(windowWidth),
 		OldHeight: int(windowHeight),
@@ -156,11 +167,88 @@ func onSizeCallback(w *glfw.Window, width int, height int) {
 
 	// get the texture of the window because it may have changed since creation
 	x, y := w.GetFramebufferSize()
-	canvasWidth = float32(x)
-	canvasHeight = float32(y)
-	retinaScale = canvasWidth / float32(width)
+	updateCanvas(x, y)
+
+	EngineMailbox.Post(message)
+}
+
+func onFramebufferSizeCallback(w *glfw.Window, width int, height int) {
+	updateCanvas(width, height)
+}
+
+// updateCanvas records the size of the framebuffer, and posts a WindowContentScaleMessage when the scale changed.
+// GLFW 3.2 has no content scale callback, so a change of DPI is detected through the framebuffer size.
+func updateCanvas(width, height int) {
+	canvasWidth = float32(width)
+	canvasHeight = float32(height)
+
+	// the window size callback may not have been invoked yet, i.e. on X11, ask for the current size
+	w, _ := window.GetSize()
+	if w == 0 || width == 0 {
+		// iconified
+		return
+	}
+
+	scale := canvasWidth / float32(w)
+	if scale != retinaScale {
+		EngineMailbox.Post(WindowContentScaleMessage{OldScale: retinaScale, NewScale: scale})
+		retinaScale = scale
+	}
+}
+
+func onFocusCallback(w *glfw.Window, focused bool) {
+	lastInput = glfw.GetTime()
+	EngineMailbox.Post(WindowFocusMessage{Focused: focused})
+}
+
+func onIconifyCallback(w *glfw.Window, iconified bool) {
+	lastInput = glfw.GetTime()
+	EngineMailbox.Post(WindowIconifyMessage{Iconified: iconified})
+}
+
+func onJoystickCallback(joy, event int) {
+	if joy >= MaxGamepads {
+		return
+	}
+	lastInput = glfw.GetTime()
+
+	connected := glfw.MonitorEvent(event) == glfw.Connected
+	message := GamepadConnectedMessage{ID: joy, Connected: connected}
+	if connected {
+		message.Name = glfw.GetJoystickName(glfw.Joystick(joy))
+	}
+	EngineMailbox.Post(message)
+}
+
+// pollGamepads reads the state of the joysticks, GLFW has no callback for their buttons and axes
+func pollGamepads() {
+	if Input.Replaying() {
+		// the recorded gamepads are fed back instead
+		return
+	}
+
+	for id := 0; id < MaxGamepads; id++ {
+		joy := glfw.Joystick1 + glfw.Joystick(id)
+		if !glfw.JoystickPresent(joy) {
+			Input.disconnectGamepad(id)
+			continue
+		}
+
+		states := glfw.GetJoystickButtons(joy)
+		buttons := make([]float32, len(states))
+		for i, state := range states {
+			if glfw.Action(state) == glfw.Press {
+				buttons[i] = 1
+			}
+		}
+		Input.setGamepad(id, glfw.GetJoystickName(joy), false, glfw.GetJoystickAxes(joy), buttons)
+	}
+}
 
-	Mailbox.Dispatch(message)
+func onCloseCallback(w *glfw.Window) {
+	// the game only closes once the request went through, see CloseEvent
+	w.SetShouldClose(false)
+	CloseEvent()
 }
 
 func (p *GLFW) setKeyMapping() {
@@ -228,7 +316,14 @@ func (p *GLFW) keyChange(window *glfw.Window, key glfw.Key, scancode int, action
 }
 
 func (p *GLFW) charChange(window *glfw.Window, char rune) {
+	lastInput = glfw.GetTime()
+
 	p.imguiIO.AddInputCharacters(string(char))
+
+	if p.imguiIO.WantTextInput() {
+		return
+	}
+	Input.typeText(string(char))
 }
 
 // DisplaySize returns the dimension of the display.
@@ -337,11 +432,22 @@ func CreateWindow(title string, width, height int) {
 	window.SetScrollCallback(mouseWheelCallback)
 	window.SetCursorPosCallback(mouseMoveCallback)
 	window.SetSizeCallback(onSizeCallback)
+	window.SetFramebufferSizeCallback(onFramebufferSizeCallback)
+	window.SetFocusCallback(onFocusCallback)
+	window.SetIconifyCallback(onIconifyCallback)
+	window.SetCloseCallback(onCloseCallback)
+	glfw.SetJoystickCallback(onJoystickCallback)
 
 	// GLFW3 can work with more than one window, so make sure we set our
 	// new window as the current context to operate on
 	window.MakeContextCurrent()
 
+	if opts.VSync {
+		glfw.SwapInterval(1)
+	} else {
+		glfw.SwapInterval(0)
+	}
+
 	// make sure that GLEW initializes all of the GL functions
 	glplus.Gl = glplus.NewContext()
 	fmt.Println("OpenGL version", glplus.Gl.Version())
@@ -359,7 +465,7 @@ func CreateWindow(title string, width, height int) {
 	window.SetCharCallback(platform.charChange)
 
 	lasttime = glfw.GetTime()
-
+	lastInput = lasttime
 }
 
 // DestroyWindow ...
@@ -370,24 +476,80 @@ func DestroyWindow() {
 
 // RunPreparation is called automatically when calling Open. It should only be called once.
 func RunPreparation(defaultScene Scene) {
+	markMainThread()
 	Time = NewClock()
 
 	SetScene(defaultScene, false)
 }
 
+// idle returns whether the main loop should run at the idle framerate
+func idle() bool {
+	if opts.IdleFPS <= 0 {
+		return false
+	}
+	return window.GetAttrib(glfw.Focused) == 0 || glfw.GetTime()-lastInput > idleAfter()
+}
+
+// waitNextFrame throttles the main loop according to the frame pacing settings in `RunOptions`
+func waitNextFrame() {
+	if opts.Unlimited {
+		lasttime = glfw.GetTime()
+		return
+	}
+
+	fps := targetFPS()
+	isIdle := idle()
+	if isIdle {
+		fps = opts.IdleFPS
+	}
+	frame := 1.0 / float64(fps)
+	next := lasttime + frame
+
+	for now := glfw.GetTime(); now < next; now = glfw.GetTime() {
+		if isIdle {
+			// wake up as soon as an input event arrives, it will be handled by the next PollEvents
+			glfw.WaitEventsTimeout(next - now)
+			if !idle() {
+				break
+			}
+		} else {
+			time.Sleep(time.Duration((next - now) * float64(time.Second)))
+		}
+	}
+
+	// start the next frame from now if we woke up early, and don't try to catch up on frames we missed
+	now := glfw.GetTime()
+	lasttime = math.Max(math.Min(next, now), now-frame)
+}
+
+// clearFrame clears the framebuffer, once per frame before the Scenes are rendered.
+func clearFrame() {
+	if gl := glplus.Gl; gl != nil {
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	}
+}
+
+// wakeMain wakes the main loop up when it waits for events in idle mode, so the functions given to postMain run.
+func wakeMain() {
+	glfw.PostEmptyEvent()
+}
+
 // RunIteration runs one iteration per frame
 func RunIteration() {
-	Time.Tick()
-
 	Input.update()
+
+	waitNextFrame()
 	glfw.PollEvents()
+	pollGamepads()
+
+	Time.Tick()
 
 	// Signal start of a new frame
 	platform.NewFrame()
 	imgui.NewFrame()
 
 	// Then update the world and all Systems
-	currentWorld.Update(Time.Delta())
+	updateScenes(Time.Delta())
 
 	// Rendering
 	imgui.Render() // This call only creates the draw data list. Actual rendering to framebuffer is done below.
@@ -400,12 +562,6 @@ func RunIteration() {
 	Input.Mouse.Action = Neutral
 
 	window.SwapBuffers()
-
-	for glfw.GetTime() < lasttime+1.0/targetFPS {
-		time.Sleep(10 * time.Millisecond)
-	}
-
-	lasttime += 1.0 / targetFPS
 }
 
 func runLoop(defaultScene Scene) {
@@ -414,137 +570,52 @@ func runLoop(defaultScene Scene) {
 	signal.Notify(c, syscall.SIGTERM)
 	go func() {
 		<-c
-		CloseEvent()
+		// handle it from the main loop, like any other close request
+		postMain(CloseEvent)
 	}()
 
 	RunPreparation(defaultScene)
-	ticker := time.NewTicker(time.Duration(int(time.Second) / 60))
 
 	// Start tick, minimize the delta
 	Time.Tick()
 
-Outer:
 	for {
-		select {
-		case <-ticker.C:
-			RunIteration()
-			if closeGame {
-				break Outer
-			}
-			if window.ShouldClose() {
-				CloseEvent()
-			}
+		RunIteration()
+		if closing() {
+			break
 		}
 	}
-	ticker.Stop()
+}
+
+// glfwKeys maps the GLFW keys to the Keys
+var glfwKeys = map[glfw.Key]Key{
+	glfw.KeyGraveAccent: Grave, glfw.KeyMinus: Dash, glfw.KeyApostrophe: Apostrophe, glfw.KeySemicolon: Semicolon,
+	glfw.KeyEqual: Equals, glfw.KeyComma: Comma, glfw.KeyPeriod: Period, glfw.KeySlash: Slash,
+	glfw.KeyBackslash: Backslash, glfw.KeyBackspace: Backspace, glfw.KeyTab: Tab, glfw.KeyCapsLock: CapsLock,
+	glfw.KeySpace: Space, glfw.KeyEnter: Enter, glfw.KeyEscape: Escape, glfw.KeyInsert: Insert,
+	glfw.KeyPrintScreen: PrintScreen, glfw.KeyDelete: Delete, glfw.KeyPageUp: PageUp, glfw.KeyPageDown: PageDown,
+	glfw.KeyHome: Home, glfw.KeyEnd: End, glfw.KeyPause: Pause, glfw.KeyScrollLock: ScrollLock,
+	glfw.KeyLeft: ArrowLeft, glfw.KeyRight: ArrowRight, glfw.KeyDown: ArrowDown, glfw.KeyUp: ArrowUp,
+	glfw.KeyLeftBracket: LeftBracket, glfw.KeyLeftShift: LeftShift, glfw.KeyLeftControl: LeftControl,
+	glfw.KeyLeftSuper: LeftSuper, glfw.KeyLeftAlt: LeftAlt, glfw.KeyRightBracket: RightBracket,
+	glfw.KeyRightShift: RightShift, glfw.KeyRightControl: RightControl, glfw.KeyRightSuper: RightSuper,
+	glfw.KeyRightAlt: RightAlt, glfw.Key0: Zero, glfw.Key1: One, glfw.Key2: Two, glfw.Key3: Three,
+	glfw.Key4: Four, glfw.Key5: Five, glfw.Key6: Six, glfw.Key7: Seven, glfw.Key8: Eight, glfw.Key9: Nine,
+	glfw.KeyF1: F1, glfw.KeyF2: F2, glfw.KeyF3: F3, glfw.KeyF4: F4, glfw.KeyF5: F5, glfw.KeyF6: F6,
+	glfw.KeyF7: F7, glfw.KeyF8: F8, glfw.KeyF9: F9, glfw.KeyF10: F10, glfw.KeyF11: F11, glfw.KeyF12: F12,
+	glfw.KeyA: A, glfw.KeyB: B, glfw.KeyC: C, glfw.KeyD: D, glfw.KeyE: E, glfw.KeyF: F, glfw.KeyG: G,
+	glfw.KeyH: H, glfw.KeyI: I, glfw.KeyJ: J, glfw.KeyK: K, glfw.KeyL: L, glfw.KeyM: M, glfw.KeyN: N,
+	glfw.KeyO: O, glfw.KeyP: P, glfw.KeyQ: Q, glfw.KeyR: R, glfw.KeyS: S, glfw.KeyT: T, glfw.KeyU: U,
+	glfw.KeyV: V, glfw.KeyW: W, glfw.KeyX: X, glfw.KeyY: Y, glfw.KeyZ: Z, glfw.KeyNumLock: NumLock,
+	glfw.KeyKPMultiply: NumMultiply, glfw.KeyKPDivide: NumDivide, glfw.KeyKPAdd: NumAdd,
+	glfw.KeyKPSubtract: NumSubtract, glfw.KeyKP0: NumZero, glfw.KeyKP1: NumOne, glfw.KeyKP2: NumTwo,
+	glfw.KeyKP3: NumThree, glfw.KeyKP4: NumFour, glfw.KeyKP5: NumFive, glfw.KeyKP6: NumSix, glfw.KeyKP7: NumSeven,
+	glfw.KeyKP8: NumEight, glfw.KeyKP9: NumNine, glfw.KeyKPDecimal: NumDecimal, glfw.KeyKPEnter: NumEnter,
+	glfw.KeyKPEqual: NumEqual,
 }
 
 func init() {
 	runtime.LockOSThread()
-
-	Grave = Key(glfw.KeyGraveAccent)
-	Dash = Key(glfw.KeyMinus)
-	Apostrophe = Key(glfw.KeyApostrophe)
-	Semicolon = Key(glfw.KeySemicolon)
-	Equals = Key(glfw.KeyEqual)
-	Comma = Key(glfw.KeyComma)
-	Period = Key(glfw.KeyPeriod)
-	Slash = Key(glfw.KeySlash)
-	Backslash = Key(glfw.KeyBackslash)
-	Backspace = Key(glfw.KeyBackspace)
-	Tab = Key(glfw.KeyTab)
-	CapsLock = Key(glfw.KeyCapsLock)
-	Space = Key(glfw.KeySpace)
-	Enter = Key(glfw.KeyEnter)
-	Escape = Key(glfw.KeyEscape)
-	Insert = Key(glfw.KeyInsert)
-	PrintScreen = Key(glfw.KeyPrintScreen)
-	Delete = Key(glfw.KeyDelete)
-	PageUp = Key(glfw.KeyPageUp)
-	PageDown = Key(glfw.KeyPageDown)
-	Home = Key(glfw.KeyHome)
-	End = Key(glfw.KeyEnd)
-	Pause = Key(glfw.KeyPause)
-	ScrollLock = Key(glfw.KeyScrollLock)
-	ArrowLeft = Key(glfw.KeyLeft)
-	ArrowRight = Key(glfw.KeyRight)
-	ArrowDown = Key(glfw.KeyDown)
-	ArrowUp = Key(glfw.KeyUp)
-	LeftBracket = Key(glfw.KeyLeftBracket)
-	LeftShift = Key(glfw.KeyLeftShift)
-	LeftControl = Key(glfw.KeyLeftControl)
-	LeftSuper = Key(glfw.KeyLeftSuper)
-	LeftAlt = Key(glfw.KeyLeftAlt)
-	RightBracket = Key(glfw.KeyRightBracket)
-	RightShift = Key(glfw.KeyRightShift)
-	RightControl = Key(glfw.KeyRightControl)
-	RightSuper = Key(glfw.KeyRightSuper)
-	RightAlt = Key(glfw.KeyRightAlt)
-	Zero = Key(glfw.Key0)
-	One = Key(glfw.Key1)
-	Two = Key(glfw.Key2)
-	Three = Key(glfw.Key3)
-	Four = Key(glfw.Key4)
-	Five = Key(glfw.Key5)
-	Six = Key(glfw.Key6)
-	Seven = Key(glfw.Key7)
-	Eight = Key(glfw.Key8)
-	Nine = Key(glfw.Key9)
-	F1 = Key(glfw.KeyF1)
-	F2 = Key(glfw.KeyF2)
-	F3 = Key(glfw.KeyF3)
-	F4 = Key(glfw.KeyF4)
-	F5 = Key(glfw.KeyF5)
-	F6 = Key(glfw.KeyF6)
-	F7 = Key(glfw.KeyF7)
-	F8 = Key(glfw.KeyF8)
-	F9 = Key(glfw.KeyF9)
-	F10 = Key(glfw.KeyF10)
-	F11 = Key(glfw.KeyF11)
-	F12 = Key(glfw.KeyF12)
-	A = Key(glfw.KeyA)
-	B = Key(glfw.KeyB)
-	C = Key(glfw.KeyC)
-	D = Key(glfw.KeyD)
-	E = Key(glfw.KeyE)
-	F = Key(glfw.KeyF)
-	G = Key(glfw.KeyG)
-	H = Key(glfw.KeyH)
-	I = Key(glfw.KeyI)
-	J = Key(glfw.KeyJ)
-	K = Key(glfw.KeyK)
-	L = Key(glfw.KeyL)
-	M = Key(glfw.KeyM)
-	N = Key(glfw.KeyN)
-	O = Key(glfw.KeyO)
-	P = Key(glfw.KeyP)
-	Q = Key(glfw.KeyQ)
-	R = Key(glfw.KeyR)
-	S = Key(glfw.KeyS)
-	T = Key(glfw.KeyT)
-	U = Key(glfw.KeyU)
-	V = Key(glfw.KeyV)
-	W = Key(glfw.KeyW)
-	X = Key(glfw.KeyX)
-	Y = Key(glfw.KeyY)
-	Z = Key(glfw.KeyZ)
-	NumLock = Key(glfw.KeyNumLock)
-	NumMultiply = Key(glfw.KeyKPMultiply)
-	NumDivide = Key(glfw.KeyKPDivide)
-	NumAdd = Key(glfw.KeyKPAdd)
-	NumSubtract = Key(glfw.KeyKPSubtract)
-	NumZero = Key(glfw.KeyKP0)
-	NumOne = Key(glfw.KeyKP1)
-	NumTwo = Key(glfw.KeyKP2)
-	NumThree = Key(glfw.KeyKP3)
-	NumFour = Key(glfw.KeyKP4)
-	NumFive = Key(glfw.KeyKP5)
-	NumSix = Key(glfw.KeyKP6)
-	NumSeven = Key(glfw.KeyKP7)
-	NumEight = Key(glfw.KeyKP8)
-	NumNine = Key(glfw.KeyKP9)
-	NumDecimal = Key(glfw.KeyKPDecimal)
-	NumEnter = Key(glfw.KeyKPEnter)
 }
 
 func openFile(url string) (io.ReadCloser, error) {
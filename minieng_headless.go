@@ -0,0 +1,136 @@
+//+build headless
+
+package minieng
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	// Backend ...
+	Backend = "Headless"
+
+	// ResizeXOffset ...
+	ResizeXOffset = float32(0)
+
+	// ResizeYOffset ...
+	ResizeYOffset = float32(0)
+
+	// HeadlessFrameDuration is the amount of time the clock advances for every iteration of the headless main loop,
+	// which runs at that pace.
+	HeadlessFrameDuration = time.Second / 60
+
+	canvasWidth  float32
+	canvasHeight float32
+)
+
+// CreateWindow does not open anything in headless mode, it only records the requested dimensions
+func CreateWindow(title string, width, height int) {
+	windowWidth = float32(width)
+	windowHeight = float32(height)
+	canvasWidth = float32(width)
+	canvasHeight = float32(height)
+}
+
+// DestroyWindow has nothing to release in headless mode
+func DestroyWindow() {}
+
+// WindowWidth ...
+func WindowWidth() float32 {
+	return windowWidth
+}
+
+// WindowHeight ...
+func WindowHeight() float32 {
+	return windowHeight
+}
+
+// CanvasWidth ...
+func CanvasWidth() float32 {
+	return canvasWidth
+}
+
+// CanvasHeight ...
+func CanvasHeight() float32 {
+	return canvasHeight
+}
+
+// CanvasScale ...
+func CanvasScale() float32 {
+	return 1
+}
+
+// SetCursor has no effect in headless mode
+func SetCursor(Cursor) {}
+
+// SetCursorVisibility has no effect in headless mode
+func SetCursorVisibility(visible bool) {}
+
+// SetTitle has no effect in headless mode
+func SetTitle(title string) {}
+
+// RunPreparation is called automatically when calling Run. It can be called directly (together with RunIteration)
+// to drive a Scene step by step, i.e. from unit tests. The clock is manual: use `Time.Advance` before every
+// `RunIteration` to choose the delta the Systems receive.
+func RunPreparation(defaultScene Scene) {
+	markMainThread()
+	if Input == nil {
+		Input = NewInputManager()
+	}
+	Time = NewManualClock()
+
+	SetScene(defaultScene, false)
+}
+
+// clearFrame does nothing, there is no framebuffer when running headless.
+func clearFrame() {}
+
+// wakeMain does nothing, the headless main loop never waits for events.
+func wakeMain() {}
+
+// RunIteration runs one iteration per frame
+func RunIteration() {
+	Time.Tick()
+
+	// Then update the world and all Systems
+	updateScenes(Time.Delta())
+
+	// reset values to avoid catching the same "signal" twice, the input for the next frame is set between
+	// iterations
+	Input.update()
+	Input.Mouse.ScrollX, Input.Mouse.ScrollY = 0, 0
+	Input.Mouse.Action = Neutral
+}
+
+func runLoop(defaultScene Scene) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		<-c
+		// handle it from the main loop, like any other close request
+		postMain(CloseEvent)
+	}()
+
+	RunPreparation(defaultScene)
+
+	// Start tick, minimize the delta
+	Time.Tick()
+
+	ticker := time.NewTicker(HeadlessFrameDuration)
+	defer ticker.Stop()
+
+	for !closing() {
+		Time.Advance(HeadlessFrameDuration)
+		RunIteration()
+		<-ticker.C
+	}
+}
+
+func openFile(url string) (io.ReadCloser, error) {
+	return os.Open(url)
+}
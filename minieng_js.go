@@ -1,4 +1,4 @@
-//+build netgo
+//+build netgo,!headless
 
 package minieng
 
@@ -10,7 +10,6 @@ import (
 	"math"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/aubonbeurre/glplus"
 	"github.com/gopherjs/gopherjs/js"
@@ -34,6 +33,83 @@ func init() {
 	rafPolyfill()
 }
 
+// domCodes maps the KeyboardEvent codes, i.e. the physical keys, to the Keys
+var domCodes = map[string]Key{
+	"Backquote": Grave, "Minus": Dash, "Quote": Apostrophe, "Semicolon": Semicolon, "Equal": Equals,
+	"Comma": Comma, "Period": Period, "Slash": Slash, "Backslash": Backslash, "Backspace": Backspace, "Tab": Tab,
+	"CapsLock": CapsLock, "Space": Space, "Enter": Enter, "Escape": Escape, "Insert": Insert,
+	"PrintScreen": PrintScreen, "Delete": Delete, "PageUp": PageUp, "PageDown": PageDown, "Home": Home,
+	"End": End, "Pause": Pause, "ScrollLock": ScrollLock, "ArrowLeft": ArrowLeft, "ArrowRight": ArrowRight,
+	"ArrowDown": ArrowDown, "ArrowUp": ArrowUp, "BracketLeft": LeftBracket, "ShiftLeft": LeftShift,
+	"ControlLeft": LeftControl, "MetaLeft": LeftSuper, "OSLeft": LeftSuper, "AltLeft": LeftAlt,
+	"BracketRight": RightBracket, "ShiftRight": RightShift, "ControlRight": RightControl, "MetaRight": RightSuper,
+	"OSRight": RightSuper, "AltRight": RightAlt, "Digit0": Zero, "Digit1": One, "Digit2": Two, "Digit3": Three,
+	"Digit4": Four, "Digit5": Five, "Digit6": Six, "Digit7": Seven, "Digit8": Eight, "Digit9": Nine, "F1": F1,
+	"F2": F2, "F3": F3, "F4": F4, "F5": F5, "F6": F6, "F7": F7, "F8": F8, "F9": F9, "F10": F10, "F11": F11,
+	"F12": F12, "KeyA": A, "KeyB": B, "KeyC": C, "KeyD": D, "KeyE": E, "KeyF": F, "KeyG": G, "KeyH": H, "KeyI": I,
+	"KeyJ": J, "KeyK": K, "KeyL": L, "KeyM": M, "KeyN": N, "KeyO": O, "KeyP": P, "KeyQ": Q, "KeyR": R, "KeyS": S,
+	"KeyT": T, "KeyU": U, "KeyV": V, "KeyW": W, "KeyX": X, "KeyY": Y, "KeyZ": Z, "NumLock": NumLock,
+	"NumpadMultiply": NumMultiply, "NumpadDivide": NumDivide, "NumpadAdd": NumAdd, "NumpadSubtract": NumSubtract,
+	"Numpad0": NumZero, "Numpad1": NumOne, "Numpad2": NumTwo, "Numpad3": NumThree, "Numpad4": NumFour,
+	"Numpad5": NumFive, "Numpad6": NumSix, "Numpad7": NumSeven, "Numpad8": NumEight, "Numpad9": NumNine,
+	"NumpadDecimal": NumDecimal, "NumpadEnter": NumEnter, "NumpadEqual": NumEqual,
+}
+
+// domKeyCodes maps the legacy KeyboardEvent key codes to the Keys, for the browsers without KeyboardEvent.code. The
+// left and right modifiers can't be told apart.
+var domKeyCodes = map[int]Key{
+	192: Grave, 189: Dash, 173: Dash, 222: Apostrophe, 186: Semicolon, 59: Semicolon, 187: Equals, 61: Equals,
+	188: Comma, 190: Period, 191: Slash, 220: Backslash, 8: Backspace, 9: Tab, 20: CapsLock, 32: Space, 13: Enter,
+	27: Escape, 45: Insert, 44: PrintScreen, 46: Delete, 33: PageUp, 34: PageDown, 36: Home, 35: End, 19: Pause,
+	145: ScrollLock, 37: ArrowLeft, 39: ArrowRight, 40: ArrowDown, 38: ArrowUp, 219: LeftBracket, 16: LeftShift,
+	17: LeftControl, 91: LeftSuper, 18: LeftAlt, 221: RightBracket, 92: RightSuper, 93: RightSuper, 48: Zero,
+	49: One, 50: Two, 51: Three, 52: Four, 53: Five, 54: Six, 55: Seven, 56: Eight, 57: Nine, 112: F1, 113: F2,
+	114: F3, 115: F4, 116: F5, 117: F6, 118: F7, 119: F8, 120: F9, 121: F10, 122: F11, 123: F12, 65: A, 66: B,
+	67: C, 68: D, 69: E, 70: F, 71: G, 72: H, 73: I, 74: J, 75: K, 76: L, 77: M, 78: N, 79: O, 80: P, 81: Q,
+	82: R, 83: S, 84: T, 85: U, 86: V, 87: W, 88: X, 89: Y, 90: Z, 144: NumLock, 106: NumMultiply, 111: NumDivide,
+	107: NumAdd, 109: NumSubtract, 96: NumZero, 97: NumOne, 98: NumTwo, 99: NumThree, 100: NumFour, 101: NumFive,
+	102: NumSix, 103: NumSeven, 104: NumEight, 105: NumNine, 110: NumDecimal,
+}
+
+// domKey returns the Key of a KeyboardEvent.
+func domKey(ke *dom.KeyboardEvent) (Key, bool) {
+	if code := ke.Underlying().Get("code"); code != js.Undefined && code.String() != "" {
+		key, ok := domCodes[code.String()]
+		return key, ok
+	}
+	key, ok := domKeyCodes[ke.KeyCode]
+	return key, ok
+}
+
+// domMouseButton returns the button of a MouseEvent, the browsers numbering the middle button before the right one.
+func domMouseButton(mm *dom.MouseEvent) MouseButton {
+	switch mm.Button {
+	case 1:
+		return MouseButtonMiddle
+	case 2:
+		return MouseButtonRight
+	}
+	return MouseButton(mm.Button)
+}
+
+// domModifiers returns the modifiers held during a MouseEvent.
+func domModifiers(mm *dom.MouseEvent) Modifier {
+	var mods Modifier
+	if mm.ShiftKey {
+		mods |= Shift
+	}
+	if mm.CtrlKey {
+		mods |= Control
+	}
+	if mm.AltKey {
+		mods |= Alt
+	}
+	if mm.MetaKey {
+		mods |= Super
+	}
+	return mods
+}
+
 //var canvas *js.Object
 var document = dom.GetWindow().Document().(dom.HTMLDocument)
 
@@ -76,19 +152,94 @@ func CreateWindow(title string, width, height int) {
 	//ResizeYOffset = gameHeight - CanvasHeight()
 
 	w := dom.GetWindow()
+	w.AddEventListener("resize", false, func(ev dom.Event) {
+		message := WindowResizeMessage{
+			OldWidth:  int(windowWidth),
+			OldHeight: int(windowHeight),
+			NewWidth:  int(WindowWidth()),
+			NewHeight: int(WindowHeight()),
+		}
+		windowWidth = WindowWidth()
+		windowHeight = WindowHeight()
+
+		EngineMailbox.Post(message)
+	})
+
+	w.AddEventListener("focus", false, func(ev dom.Event) {
+		EngineMailbox.Post(WindowFocusMessage{Focused: true})
+	})
+
+	w.AddEventListener("blur", false, func(ev dom.Event) {
+		EngineMailbox.Post(WindowFocusMessage{Focused: false})
+	})
+
+	w.AddEventListener("gamepadconnected", false, func(ev dom.Event) {
+		pad := ev.Underlying().Get("gamepad")
+		EngineMailbox.Post(GamepadConnectedMessage{ID: pad.Get("index").Int(), Name: pad.Get("id").String(), Connected: true})
+	})
+
+	w.AddEventListener("gamepaddisconnected", false, func(ev dom.Event) {
+		pad := ev.Underlying().Get("gamepad")
+		EngineMailbox.Post(GamepadConnectedMessage{ID: pad.Get("index").Int(), Name: pad.Get("id").String()})
+	})
+
+	for event, phase := range map[string]TouchPhase{
+		"touchstart":  TouchBegin,
+		"touchmove":   TouchMove,
+		"touchend":    TouchEnd,
+		"touchcancel": TouchCancel,
+	} {
+		phase := phase
+		w.AddEventListener(event, false, func(ev dom.Event) {
+			touches := ev.Underlying().Get("changedTouches")
+			for i := 0; i < touches.Length(); i++ {
+				t := touches.Index(i)
+				x := float32(t.Get("clientX").Float() * devicePixelRatio)
+				y := float32(t.Get("clientY").Float() * devicePixelRatio)
+				Input.touch(t.Get("identifier").Int64(), phase, x, y)
+			}
+		})
+	}
+
+	// the text typed in an editable element is reported both by keypress and input, only keep the first one
+	typed := false
+
 	w.AddEventListener("keypress", false, func(ev dom.Event) {
-		// TODO: Not sure what to do here, come back
-		//ke := ev.(*dom.KeyboardEvent)
-		//responser.Type(rune(keyStates[Key(ke.KeyCode)]))
+		ke := ev.(*dom.KeyboardEvent)
+		if ke.CharCode != 0 && !ke.CtrlKey && !ke.MetaKey {
+			Input.typeText(string(rune(ke.CharCode)))
+			typed = true
+		}
+	})
+
+	w.AddEventListener("input", false, func(ev dom.Event) {
+		e := ev.Underlying()
+		if typed || e.Get("isComposing").Bool() || e.Get("inputType").String() != "insertText" {
+			typed = false
+			return
+		}
+		Input.typeText(e.Get("data").String())
+	})
+
+	w.AddEventListener("compositionupdate", false, func(ev dom.Event) {
+		Input.compose(ev.Underlying().Get("data").String())
+	})
+
+	w.AddEventListener("compositionend", false, func(ev dom.Event) {
+		Input.compose("")
+		Input.typeText(ev.Underlying().Get("data").String())
 	})
 	w.AddEventListener("keydown", false, func(ev dom.Event) {
-		ke := ev.(*dom.KeyboardEvent)
-		Input.keys.Set(Key(ke.KeyCode), true)
+		typed = false
+		if key, ok := domKey(ev.(*dom.KeyboardEvent)); ok {
+			Input.keys.Set(key, true)
+		}
 	})
 
 	w.AddEventListener("keyup", false, func(ev dom.Event) {
-		ke := ev.(*dom.KeyboardEvent)
-		Input.keys.Set(Key(ke.KeyCode), false)
+		if key, ok := domKey(ev.(*dom.KeyboardEvent)); ok {
+			Input.keys.Set(key, false)
+		}
 	})
 
 	w.AddEventListener("mousemove", false, func(ev dom.Event) {
@@ -102,14 +253,14 @@ func CreateWindow(title string, width, height int) {
 		mm := ev.(*dom.MouseEvent)
 		Input.Mouse.X = float32(float64(mm.ClientX) * devicePixelRatio)
 		Input.Mouse.Y = float32(float64(mm.ClientY) * devicePixelRatio)
-		Input.Mouse.Action = Press
+		Input.mouseButton(domMouseButton(mm), Press, domModifiers(mm))
 	})
 
 	w.AddEventListener("mouseup", false, func(ev dom.Event) {
 		mm := ev.(*dom.MouseEvent)
 		Input.Mouse.X = float32(float64(mm.ClientX) * devicePixelRatio)
 		Input.Mouse.Y = float32(float64(mm.ClientY) * devicePixelRatio)
-		Input.Mouse.Action = Release
+		Input.mouseButton(domMouseButton(mm), Release, domModifiers(mm))
 	})
 }
 
@@ -200,11 +351,21 @@ func rafPolyfill() {
 	}
 }
 
+// clearFrame clears the framebuffer, once per frame before the Scenes are rendered.
+func clearFrame() {
+	if gl := glplus.Gl; gl != nil {
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	}
+}
+
 // RunIteration runs one iteration per frame
 func RunIteration() {
 	Time.Tick()
+	pollGamepads()
+	updateScenes(Time.Delta())
+
+	// the input of the next frame arrives between iterations, forget this one now
 	Input.update()
-	currentWorld.Update(Time.Delta())
 	Input.Mouse.Action = Neutral
 	// TODO: this may not work, and sky-rocket the FPS
 	//  requestAnimationFrame(func(dt float32) {
@@ -218,6 +379,41 @@ func RunIteration() {
 	// })
 }
 
+// pollGamepads reads the state of the gamepads through the browser Gamepad API
+func pollGamepads() {
+	if Input.Replaying() {
+		// the recorded gamepads are fed back instead
+		return
+	}
+
+	navigator := js.Global.Get("navigator")
+	if navigator.Get("getGamepads") == js.Undefined {
+		return
+	}
+
+	pads := navigator.Call("getGamepads")
+	for id := 0; id < MaxGamepads; id++ {
+		var pad *js.Object
+		if id < pads.Length() {
+			pad = pads.Index(id)
+		}
+		if pad == nil || pad == js.Undefined || !pad.Get("connected").Bool() {
+			Input.disconnectGamepad(id)
+			continue
+		}
+
+		axes := make([]float32, pad.Get("axes").Length())
+		for i := range axes {
+			axes[i] = float32(pad.Get("axes").Index(i).Float())
+		}
+		buttons := make([]float32, pad.Get("buttons").Length())
+		for i := range buttons {
+			buttons[i] = float32(pad.Get("buttons").Index(i).Get("value").Float())
+		}
+		Input.setGamepad(id, pad.Get("id").String(), pad.Get("mapping").String() == "standard", axes, buttons)
+	}
+}
+
 func requestAnimationFrame(callback func(float32)) int {
 	//return dom.GetWindow().RequestAnimationFrame(callback)
 	return js.Global.Call("requestAnimationFrame", callback).Int()
@@ -229,34 +425,55 @@ func cancelAnimationFrame(id int) {
 
 // RunPreparation is called automatically when calling Open. It should only be called once.
 func RunPreparation() {
+	markMainThread()
 	Time = NewClock()
 
-	dom.GetWindow().AddEventListener("onbeforeunload", false, func(e dom.Event) {
-		dom.GetWindow().Alert("You're closing")
+	dom.GetWindow().AddEventListener("beforeunload", false, func(e dom.Event) {
+		CloseEvent()
+		if closing() {
+			return
+		}
+
+		// the page can't wait for the Scene to decide, let the browser ask for a confirmation instead
+		e.PreventDefault()
+		e.Underlying().Set("returnValue", "")
 	})
 }
 
+// wakeMain does nothing, the web main loop runs on every animation frame.
+func wakeMain() {}
+
 func runLoop(defaultScene Scene) {
 	SetScene(defaultScene, false)
 	RunPreparation()
-	ticker := time.NewTicker(time.Duration(int(time.Second) / 60))
+
+	// the frames follow requestAnimationFrame, and are only throttled when a TargetFPS is set
+	frames := make(chan float64, 1)
+	onFrame := func(timestamp float64) {
+		select {
+		case frames <- timestamp:
+		default:
+		}
+	}
 
 	// Start tick, minimize the delta
 	Time.Tick()
 
-Outer:
-	for {
-		select {
-		case <-ticker.C:
-			if closeGame {
-				break Outer
-			}
-			RunIteration()
+	var last float64
+	for !closing() {
+		js.Global.Call("requestAnimationFrame", onFrame)
+		timestamp := <-frames
+		if opts.TargetFPS > 0 && timestamp-last < 1000/float64(opts.TargetFPS)-frameTolerance {
+			continue
 		}
+		last = timestamp
+		RunIteration()
 	}
-	ticker.Stop()
 }
 
+// frameTolerance is how early, in milliseconds, an animation frame may come and still be used when throttling
+const frameTolerance = 1
+
 func openFile(url string) (io.ReadCloser, error) {
 	req := xhr.NewRequest("GET", url)
 
@@ -1,4 +1,4 @@
-//+build android
+//+build android,!headless
 
 package minieng
 
@@ -13,6 +13,7 @@ import (
 	"github.com/aubonbeurre/glplus"
 	"golang.org/x/mobile/app"
 	"golang.org/x/mobile/asset"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
@@ -37,6 +38,33 @@ var (
 	Backend string = "Mobile"
 )
 
+// mobileKeys maps the key codes of the physical keyboards to the Keys
+var mobileKeys = map[key.Code]Key{
+	key.CodeGraveAccent: Grave, key.CodeHyphenMinus: Dash, key.CodeApostrophe: Apostrophe,
+	key.CodeSemicolon: Semicolon, key.CodeEqualSign: Equals, key.CodeComma: Comma, key.CodeFullStop: Period,
+	key.CodeSlash: Slash, key.CodeBackslash: Backslash, key.CodeDeleteBackspace: Backspace, key.CodeTab: Tab,
+	key.CodeCapsLock: CapsLock, key.CodeSpacebar: Space, key.CodeReturnEnter: Enter, key.CodeEscape: Escape,
+	key.CodeInsert: Insert, key.CodeDeleteForward: Delete, key.CodePageUp: PageUp, key.CodePageDown: PageDown,
+	key.CodeHome: Home, key.CodeEnd: End, key.CodePause: Pause, key.CodeLeftArrow: ArrowLeft,
+	key.CodeRightArrow: ArrowRight, key.CodeDownArrow: ArrowDown, key.CodeUpArrow: ArrowUp,
+	key.CodeLeftSquareBracket: LeftBracket, key.CodeLeftShift: LeftShift, key.CodeLeftControl: LeftControl,
+	key.CodeLeftGUI: LeftSuper, key.CodeLeftAlt: LeftAlt, key.CodeRightSquareBracket: RightBracket,
+	key.CodeRightShift: RightShift, key.CodeRightControl: RightControl, key.CodeRightGUI: RightSuper,
+	key.CodeRightAlt: RightAlt, key.Code0: Zero, key.Code1: One, key.Code2: Two, key.Code3: Three,
+	key.Code4: Four, key.Code5: Five, key.Code6: Six, key.Code7: Seven, key.Code8: Eight, key.Code9: Nine,
+	key.CodeF1: F1, key.CodeF2: F2, key.CodeF3: F3, key.CodeF4: F4, key.CodeF5: F5, key.CodeF6: F6,
+	key.CodeF7: F7, key.CodeF8: F8, key.CodeF9: F9, key.CodeF10: F10, key.CodeF11: F11, key.CodeF12: F12,
+	key.CodeA: A, key.CodeB: B, key.CodeC: C, key.CodeD: D, key.CodeE: E, key.CodeF: F, key.CodeG: G,
+	key.CodeH: H, key.CodeI: I, key.CodeJ: J, key.CodeK: K, key.CodeL: L, key.CodeM: M, key.CodeN: N,
+	key.CodeO: O, key.CodeP: P, key.CodeQ: Q, key.CodeR: R, key.CodeS: S, key.CodeT: T, key.CodeU: U,
+	key.CodeV: V, key.CodeW: W, key.CodeX: X, key.CodeY: Y, key.CodeZ: Z, key.CodeKeypadNumLock: NumLock,
+	key.CodeKeypadAsterisk: NumMultiply, key.CodeKeypadSlash: NumDivide, key.CodeKeypadPlusSign: NumAdd,
+	key.CodeKeypadHyphenMinus: NumSubtract, key.CodeKeypad0: NumZero, key.CodeKeypad1: NumOne,
+	key.CodeKeypad2: NumTwo, key.CodeKeypad3: NumThree, key.CodeKeypad4: NumFour, key.CodeKeypad5: NumFive,
+	key.CodeKeypad6: NumSix, key.CodeKeypad7: NumSeven, key.CodeKeypad8: NumEight, key.CodeKeypad9: NumNine,
+	key.CodeKeypadFullStop: NumDecimal, key.CodeKeypadEnter: NumEnter, key.CodeKeypadEqualSign: NumEqual,
+}
+
 // CreateWindow creates a window with the specified parameters
 func CreateWindow(title string, width, height int) {
 	msaaPreference = 0
@@ -93,7 +121,8 @@ func runLoop(defaultScene Scene) {
 	signal.Notify(c, syscall.SIGTERM)
 	go func() {
 		<-c
-		CloseEvent()
+		// handle it from the main loop, like any other close request
+		postMain(CloseEvent)
 	}()
 
 	app.Main(func(a app.App) {
@@ -105,6 +134,15 @@ func runLoop(defaultScene Scene) {
 		for e := range a.Events() {
 			switch e := a.Filter(e).(type) {
 			case lifecycle.Event:
+				switch e.Crosses(lifecycle.StageFocused) {
+				case lifecycle.CrossOn:
+					EngineMailbox.Post(WindowFocusMessage{Focused: true})
+				case lifecycle.CrossOff:
+					// x/mobile sends no touch.TypeEnd for the fingers still down
+					Input.cancelTouches()
+					EngineMailbox.Post(WindowFocusMessage{Focused: false})
+				}
+
 				switch e.Crosses(lifecycle.StageVisible) {
 				case lifecycle.CrossOn:
 					Gl = glplus.NewContext(e.DrawContext)
@@ -120,11 +158,25 @@ func runLoop(defaultScene Scene) {
 					// Let the device know we want to start painting :-)
 					a.Send(paint.Event{})
 				case lifecycle.CrossOff:
-					CloseEvent()
+					// sent to the background, not closed
 					Gl = nil
 				}
 
+				if e.Crosses(lifecycle.StageDead) == lifecycle.CrossOn {
+					// the app is being destroyed, the Scenes can't cancel it
+					CloseEvent()
+					return
+				}
+
 			case size.Event:
+				if windowWidth != float32(e.WidthPx) || windowHeight != float32(e.HeightPx) {
+					EngineMailbox.Post(WindowResizeMessage{
+						OldWidth:  int(windowWidth),
+						OldHeight: int(windowHeight),
+						NewWidth:  e.WidthPx,
+						NewHeight: e.HeightPx,
+					})
+				}
 				sz = e
 				windowWidth = float32(sz.WidthPx)
 				windowHeight = float32(sz.HeightPx)
@@ -140,6 +192,9 @@ func runLoop(defaultScene Scene) {
 				}
 
 				RunIteration()
+				if closing() {
+					return
+				}
 
 				fps.Draw(sz)
 
@@ -149,16 +204,37 @@ func runLoop(defaultScene Scene) {
 				// Drive the animation by preparing to paint the next frame
 				// after this one is shown. - FPS is ignored here!
 				a.Send(paint.Event{})
+			case key.Event:
+				if k, ok := mobileKeys[e.Code]; ok && e.Direction != key.DirNone {
+					Input.keys.Set(k, e.Direction == key.DirPress)
+				}
+				if e.Direction != key.DirRelease && e.Rune >= 0 {
+					Input.typeText(string(e.Rune))
+				}
 			case touch.Event:
+				id := int64(e.Sequence)
+				switch e.Type {
+				case touch.TypeBegin:
+					Input.touch(id, TouchBegin, e.X, e.Y)
+				case touch.TypeMove:
+					Input.touch(id, TouchMove, e.X, e.Y)
+				case touch.TypeEnd:
+					Input.touch(id, TouchEnd, e.X, e.Y)
+				}
+
+				// the mouse follows the first finger
+				if !Input.isPrimaryTouch(id) {
+					continue
+				}
 				Input.Mouse.X = e.X
 				Input.Mouse.Y = e.Y
 				switch e.Type {
 				case touch.TypeBegin:
-					Input.Mouse.Action = Press
+					Input.mouseButton(MouseButtonLeft, Press, 0)
 				case touch.TypeMove:
 					Input.Mouse.Action = Move
 				case touch.TypeEnd:
-					Input.Mouse.Action = Release
+					Input.mouseButton(MouseButtonLeft, Release, 0)
 				}
 			}
 		}
@@ -168,19 +244,30 @@ func runLoop(defaultScene Scene) {
 // RunPreparation is called only once, and is called automatically when calling Open
 // It is only here for benchmarking in combination with OpenHeadlessNoRun
 func RunPreparation(defaultScene Scene) {
+	markMainThread()
 	Time = NewClock()
 	SetScene(defaultScene, false)
 }
 
+// clearFrame clears the framebuffer, once per frame before the Scenes are rendered.
+func clearFrame() {
+	if gl := glplus.Gl; gl != nil {
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	}
+}
+
+// wakeMain does nothing, the mobile main loop keeps painting.
+func wakeMain() {}
+
 // RunIteration runs one iteration / frame
 func RunIteration() {
 	Time.Tick()
 
-	Input.update()
-
 	// Then update the world and all Systems
-	currentWorld.Update(Time.Delta())
+	updateScenes(Time.Delta())
 
+	// the input of the next frame arrives between iterations, forget this one now
+	Input.update()
 }
 
 // SetCursor changes the cursor - not yet implemented
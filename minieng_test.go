@@ -0,0 +1,59 @@
+//+build headless
+
+package minieng
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// funcSystem calls update every frame
+type funcSystem struct {
+	update func(dt float32)
+}
+
+func (s *funcSystem) Update(dt float32) { s.update(dt) }
+func (*funcSystem) Remove(BasicEntity)  {}
+
+// testScene adds its Systems on Setup
+type testScene struct {
+	name    string
+	systems []System
+}
+
+func (*testScene) Preload() {}
+
+func (s *testScene) Setup(w *World) {
+	for _, system := range s.systems {
+		w.AddSystem(system)
+	}
+}
+
+func (s *testScene) Type() string { return s.name }
+
+func TestRunHeadless(t *testing.T) {
+	defer atomic.StoreInt32(&closeGame, 0)
+
+	frames := 0
+	frame := &funcSystem{func(dt float32) {
+		if want := float32(HeadlessFrameDuration.Seconds()); dt != want {
+			t.Errorf("frame %d: dt = %v, want %v", frames, dt, want)
+		}
+		if frames++; frames == 3 {
+			Exit()
+		}
+	}}
+
+	start := time.Now()
+	Run(RunOptions{Width: 320, Height: 240}, &testScene{name: "runHeadless", systems: []System{frame}})
+	if frames != 3 {
+		t.Errorf("Run returned after %d frames, want 3", frames)
+	}
+	if elapsed := time.Since(start); elapsed < 2*HeadlessFrameDuration {
+		t.Errorf("3 frames took %v, the loop is not paced", elapsed)
+	}
+	if w, h := WindowWidth(), WindowHeight(); w != 320 || h != 240 {
+		t.Errorf("window is %vx%v, want 320x240", w, h)
+	}
+}
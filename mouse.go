@@ -0,0 +1,71 @@
+package minieng
+
+import "strconv"
+
+// mouseKeyBase is the first Key used for the mouse buttons, above the keyboard keys and below the gamepad buttons
+const mouseKeyBase Key = 1 << 15
+
+// mouseButtonNames are the names of the mouse buttons, the other ones are numbered
+var mouseButtonNames = map[MouseButton]string{
+	MouseButtonLeft:   "Left",
+	MouseButtonRight:  "Right",
+	MouseButtonMiddle: "Middle",
+}
+
+// String returns the name of the mouse button, i.e. "Left" or "4".
+func (b MouseButton) String() string {
+	if name, ok := mouseButtonNames[b]; ok {
+		return name
+	}
+	return strconv.Itoa(int(b) + 1)
+}
+
+// MouseKey returns the Key standing for the given mouse button, so that it can be used as a Button trigger like any
+// keyboard key, i.e. with a Chord for Shift+Click:
+//
+//    minieng.Input.RegisterButton("fire", minieng.Space, minieng.MouseKey(minieng.MouseButtonLeft))
+//    minieng.Input.RegisterChord("select", minieng.Shift, minieng.MouseKey(minieng.MouseButtonLeft))
+func MouseKey(b MouseButton) Key {
+	return mouseKeyBase + Key(b)
+}
+
+// MouseEvent is a mouse button pressed or released.
+type MouseEvent struct {
+	Button MouseButton
+	// Action is either Press or Release
+	Action   Action
+	X, Y     float32
+	Modifier Modifier
+}
+
+// MouseButton returns the state of the given mouse button. Unlike Mouse.Action, a press and a release of the same
+// button within a single frame are seen in two frames, and the buttons are tracked separately.
+func (im *InputManager) MouseButton(b MouseButton) KeyState {
+	return im.keys.Get(MouseKey(b))
+}
+
+// MouseEvents returns every mouse button pressed or released during the current frame, in order.
+func (im *InputManager) MouseEvents() []MouseEvent {
+	if im.blocked {
+		return nil
+	}
+	return im.mouseEvents
+}
+
+// mouseButton records a mouse button pressed or released, as reported by the backend, at the current position of
+// the Mouse.
+func (im *InputManager) mouseButton(b MouseButton, action Action, mods Modifier) {
+	im.Mouse.Button = b
+	im.Mouse.Action = action
+	im.Mouse.Modifer = mods
+	im.keys.Set(MouseKey(b), action == Press)
+
+	if im.replay != nil {
+		return
+	}
+	e := MouseEvent{Button: b, Action: action, X: im.Mouse.X, Y: im.Mouse.Y, Modifier: mods}
+	im.mouseEvents = append(im.mouseEvents, e)
+	if im.recorder != nil {
+		im.recorder.frame.MouseEvents = append(im.recorder.frame.MouseEvents, e)
+	}
+}
@@ -0,0 +1,59 @@
+//+build headless
+
+package minieng
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMouseButtons(t *testing.T) {
+	defer func(input *InputManager) { Input = input }(Input)
+	Input = NewInputManager()
+	Input.RegisterButton("fire", MouseKey(MouseButtonLeft))
+
+	Input.Mouse.X, Input.Mouse.Y = 3, 4
+	Input.mouseButton(MouseButtonLeft, Press, Shift)
+	Input.mouseButton(MouseButtonRight, Press, 0)
+	Input.mouseButton(MouseButtonLeft, Release, 0)
+
+	want := []MouseEvent{
+		{Button: MouseButtonLeft, Action: Press, X: 3, Y: 4, Modifier: Shift},
+		{Button: MouseButtonRight, Action: Press, X: 3, Y: 4},
+		{Button: MouseButtonLeft, Action: Release, X: 3, Y: 4},
+	}
+	if events := Input.MouseEvents(); !reflect.DeepEqual(events, want) {
+		t.Errorf("MouseEvents() = %+v, want %+v", events, want)
+	}
+	if !Input.MouseButton(MouseButtonLeft).JustPressed() || !Input.Button("fire").JustPressed() {
+		t.Error("the left button was not pressed during the first frame")
+	}
+	if !Input.MouseButton(MouseButtonRight).JustPressed() {
+		t.Error("the right button was not pressed during the first frame")
+	}
+
+	Input.setBlocked(true)
+	if events := Input.MouseEvents(); events != nil {
+		t.Errorf("MouseEvents() = %v while blocked, want none", events)
+	}
+	Input.setBlocked(false)
+
+	Input.update()
+	if !Input.MouseButton(MouseButtonLeft).JustReleased() || !Input.Button("fire").JustReleased() {
+		t.Error("the left button was not released during the second frame")
+	}
+	if !Input.MouseButton(MouseButtonRight).Down() {
+		t.Error("the right button is not down during the second frame")
+	}
+	if events := Input.MouseEvents(); len(events) != 0 {
+		t.Errorf("MouseEvents() = %v during the second frame, want none", events)
+	}
+}
+
+func TestMouseButtonString(t *testing.T) {
+	for b, want := range map[MouseButton]string{MouseButtonLeft: "Left", MouseButtonMiddle: "Middle", MouseButton(3): "4"} {
+		if name := b.String(); name != want {
+			t.Errorf("%d.String() = %q, want %q", b, name, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package minieng
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// inputFrame is the input received during a single frame
+type inputFrame struct {
+	// Frame is the number of the frame, see Clock.Frame
+	Frame uint64
+	// Delta is the Clock delta of the frame, in nanoseconds
+	Delta int64
+	// Keys are the calls to KeyManager.Set, in order
+	Keys []keyEvent
+	// Mouse is nil when the Mouse didn't change since the previous frame
+	Mouse *Mouse
+	// MouseEvents are the mouse buttons pressed or released, see InputManager.MouseEvents
+	MouseEvents []MouseEvent
+	// Text is the text typed, see InputManager.Text
+	Text string
+	// Composition is nil when the text being composed didn't change since the previous frame
+	Composition *string
+	// Touches are the changes of the Touches, in order
+	Touches []touchEvent
+	// Gamepads are the gamepads whose axes or connection changed since the previous frame
+	Gamepads []gamepadState
+}
+
+// keyEvent is a call to KeyManager.Set
+type keyEvent struct {
+	Key  Key
+	Down bool
+}
+
+// touchEvent is a call to InputManager.touch
+type touchEvent struct {
+	ID    int64
+	Phase TouchPhase
+	X, Y  float32
+}
+
+// gamepadState is the state of a gamepad besides its buttons, which are recorded as keys
+type gamepadState struct {
+	ID        int
+	Name      string
+	Connected bool
+	Axes      [gamepadAxisCount]float32
+}
+
+// inputRecorder writes the input of every frame as it happens, so the recording survives a crash
+type inputRecorder struct {
+	file        io.Closer
+	gzip        *gzip.Writer
+	enc         *gob.Encoder
+	frame       inputFrame
+	mouse       Mouse
+	composition string
+	gamepads    [MaxGamepads]gamepadState
+}
+
+// inputReplay feeds recorded frames back
+type inputReplay struct {
+	dec   *gob.Decoder
+	file  io.Closer
+	mouse Mouse
+}
+
+// ReplayEndedMessage is a message that's being dispatched once every recorded frame has been replayed, the input
+// coming from the devices again. It is posted to EngineMailbox.
+type ReplayEndedMessage struct{}
+
+// Type returns the type of the current object "ReplayEndedMessage"
+func (ReplayEndedMessage) Type() string { return "ReplayEndedMessage" }
+
+// Record starts recording the keys, the Mouse, the text typed, the Touches and the gamepads, together with the Clock
+// delta, of every frame into w, until StopRecording is called. w is closed by StopRecording when it is an io.Closer. The
+// recording can be fed back with Replay; to reproduce a run it has to start with the first frame, see
+// RunOptions.RecordInput.
+func (im *InputManager) Record(w io.Writer) error {
+	if im.recorder != nil {
+		return fmt.Errorf("input already being recorded")
+	}
+
+	gz := gzip.NewWriter(w)
+	im.recorder = &inputRecorder{gzip: gz, enc: gob.NewEncoder(gz), mouse: im.Mouse, composition: im.composition}
+	for id, g := range im.gamepads {
+		im.recorder.gamepads[id] = g.state(id)
+	}
+	if closer, ok := w.(io.Closer); ok {
+		im.recorder.file = closer
+	}
+	im.keys.setRecord(im.recorder.key)
+	return nil
+}
+
+// StopRecording ends the recording started by Record.
+func (im *InputManager) StopRecording() error {
+	r := im.recorder
+	if r == nil {
+		return nil
+	}
+	im.recorder = nil
+	im.keys.setRecord(nil)
+
+	err := r.gzip.Close()
+	if r.file != nil {
+		if closeErr := r.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Replay feeds the input recorded by Record back, starting with the next frame: the input of the devices is ignored
+// until the end of the recording, when a ReplayEndedMessage is posted. r is closed at the end when it is an
+// io.Closer. The Systems get the recorded deltas, so the replay is deterministic as long as the Scenes only depend
+// on the input and on those deltas.
+func (im *InputManager) Replay(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("unable to read the input recording: %v", err)
+	}
+
+	im.replay = &inputReplay{dec: gob.NewDecoder(gz), mouse: im.Mouse}
+	if closer, ok := r.(io.Closer); ok {
+		im.replay.file = closer
+	}
+	im.keys.setReplaying(true)
+	return nil
+}
+
+// Replaying returns whether the input is being replayed.
+func (im *InputManager) Replaying() bool {
+	return im.replay != nil
+}
+
+// key records a call to KeyManager.Set.
+func (r *inputRecorder) key(k Key, down bool) {
+	r.frame.Keys = append(r.frame.Keys, keyEvent{Key: k, Down: down})
+}
+
+// inputFrame records or replays the input of the current frame. It returns the delta the Systems should receive.
+func (im *InputManager) inputFrame(dt float32) float32 {
+	if r := im.recorder; r != nil {
+		r.frame.Frame = currentFrame()
+		r.frame.Delta = int64(float64(dt) * float64(secondsInNano))
+		if Time != nil {
+			r.frame.Delta = Time.deltaStamp
+		}
+		if im.Mouse != r.mouse {
+			mouse := im.Mouse
+			r.frame.Mouse = &mouse
+			r.mouse = mouse
+		}
+		if im.composition != r.composition {
+			composition := im.composition
+			r.frame.Composition = &composition
+			r.composition = composition
+		}
+		for id, g := range im.gamepads {
+			if state := g.state(id); state != r.gamepads[id] {
+				r.frame.Gamepads = append(r.frame.Gamepads, state)
+				r.gamepads[id] = state
+			}
+		}
+
+		err := r.enc.Encode(&r.frame)
+		if err == nil {
+			err = r.gzip.Flush()
+		}
+		if err != nil {
+			log.Println("[WARNING] unable to record the input:", err)
+			im.StopRecording()
+		}
+		r.frame = inputFrame{}
+	}
+
+	p := im.replay
+	if p == nil {
+		return dt
+	}
+
+	var frame inputFrame
+	if err := p.dec.Decode(&frame); err != nil {
+		if err != io.EOF {
+			log.Println("[WARNING] unable to replay the input:", err)
+		}
+		im.stopReplay()
+		return dt
+	}
+
+	for _, key := range frame.Keys {
+		im.keys.set(key.Key, key.Down)
+	}
+	if frame.Mouse != nil {
+		p.mouse = *frame.Mouse
+	}
+	im.Mouse = p.mouse
+	im.mouseEvents = frame.MouseEvents
+	im.addText(frame.Text)
+	if frame.Composition != nil {
+		im.setComposition(*frame.Composition)
+	}
+	for _, t := range frame.Touches {
+		im.applyTouch(t.ID, t.Phase, t.X, t.Y)
+	}
+	for _, state := range frame.Gamepads {
+		g := &im.gamepads[state.ID]
+		g.ID, g.Name, g.Connected, g.axes, g.keys = state.ID, state.Name, state.Connected, state.Axes, im.keys
+	}
+	if Time != nil {
+		Time.deltaStamp = frame.Delta
+	}
+	return float32(float64(frame.Delta) / float64(secondsInNano))
+}
+
+// stopReplay gives the input back to the devices.
+func (im *InputManager) stopReplay() {
+	if im.replay.file != nil {
+		im.replay.file.Close()
+	}
+	im.replay = nil
+	im.keys.setReplaying(false)
+	EngineMailbox.Post(ReplayEndedMessage{})
+}
+
+// startInputFiles starts the recording or the replay requested in the RunOptions.
+func startInputFiles(o RunOptions) {
+	if o.ReplayInput != "" {
+		f, err := os.Open(o.ReplayInput)
+		if err == nil {
+			if err = Input.Replay(f); err != nil {
+				f.Close()
+			}
+		}
+		if err != nil {
+			log.Println("[WARNING] unable to replay the input:", err)
+		}
+	}
+
+	if o.RecordInput != "" {
+		f, err := os.Create(o.RecordInput)
+		if err == nil {
+			err = Input.Record(f)
+		}
+		if err != nil {
+			log.Println("[WARNING] unable to record the input:", err)
+		}
+	}
+}
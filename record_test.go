@@ -0,0 +1,86 @@
+//+build headless
+
+package minieng
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// inputTestFrame runs a frame of the InputManager, the clock advancing by delay
+func inputTestFrame(im *InputManager, delay time.Duration) float32 {
+	Time.Advance(delay)
+	Time.Tick()
+	dt := im.inputFrame(Time.Delta())
+	im.update()
+	return dt
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	Time.Tick()
+
+	var recording bytes.Buffer
+	recorder := NewInputManager()
+	if err := recorder.Record(&recording); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := recorder.Record(&recording); err == nil {
+		t.Error("recording twice succeeded")
+	}
+
+	recorder.keys.Set(Space, true)
+	recorder.Mouse.X, recorder.Mouse.Y = 5, 6
+	recorder.typeText("hi")
+	recorder.touch(1, TouchBegin, 10, 10)
+	inputTestFrame(recorder, 16*time.Millisecond)
+	recorder.keys.Set(Space, false)
+	inputTestFrame(recorder, 32*time.Millisecond)
+	if err := recorder.StopRecording(); err != nil {
+		t.Fatalf("StopRecording: %v", err)
+	}
+
+	var ended bool
+	sub := Subscribe(EngineMailbox, func(ReplayEndedMessage) { ended = true })
+	defer sub.Cancel()
+
+	replayer := NewInputManager()
+	if err := replayer.Replay(&recording); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	// the devices are ignored meanwhile
+	replayer.keys.Set(Enter, true)
+	replayer.typeText("x")
+
+	Time.Advance(time.Second)
+	Time.Tick()
+	dt := replayer.inputFrame(Time.Delta())
+	if dt != 0.016 {
+		t.Errorf("first frame replayed with dt = %v, want 0.016", dt)
+	}
+	if replayer.Text() != "hi" || replayer.Mouse.X != 5 || replayer.Mouse.Y != 6 || len(replayer.Touches) != 1 {
+		t.Errorf("replayed text %q, mouse at %v,%v and %d touches, want hi, 5,6 and 1", replayer.Text(), replayer.Mouse.X, replayer.Mouse.Y, len(replayer.Touches))
+	}
+	replayer.update()
+	if down := replayer.keys.Get(Space).Down(); !down {
+		t.Error("Space is not down once the first frame is replayed")
+	}
+	if replayer.keys.Get(Enter).Down() {
+		t.Error("a key of the devices got through the replay")
+	}
+
+	if dt := inputTestFrame(replayer, time.Second); dt != 0.032 {
+		t.Errorf("second frame replayed with dt = %v, want 0.032", dt)
+	}
+	if replayer.keys.Get(Space).Down() {
+		t.Error("Space is still down once released in the recording")
+	}
+
+	inputTestFrame(replayer, 0)
+	EngineMailbox.Flush()
+	if replayer.Replaying() || !ended {
+		t.Errorf("Replaying() = %v and ReplayEndedMessage posted: %v once every frame is replayed, want false, true", replayer.Replaying(), ended)
+	}
+}
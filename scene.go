@@ -2,9 +2,15 @@ package minieng
 
 import (
 	"fmt"
+	"log"
 )
 
-var scenes = make(map[string]*sceneWrapper)
+var (
+	scenes = make(map[string]*sceneWrapper)
+
+	// sceneStack holds the active Scene and the ones it was pushed over, the active one being the last
+	sceneStack []sceneLayer
+)
 
 // Scene represents a screen ingame.
 // i.e.: main menu, settings, but also the game itself
@@ -39,15 +45,86 @@ type Exiter interface {
 	// Exit is called when the user or the system requests to close the game
 	// This should be used to cleanup or prompt user if they're sure they want to close
 	// To prevent the default action (close/exit) make sure to set OverrideCloseAction in
-	// your RunOpts to `true`. You should then handle the exiting of the program by calling
-	//    engo.Exit()
+	// your RunOptions to `true`. You should then handle the exiting of the program by calling
+	//    minieng.Exit()
 	Exit()
 }
 
+// Overlay describes how a Scene pushed with PushScene interacts with the Scenes below it. The zero value
+// pauses and hides them, and keeps the input for the pushed Scene.
+type Overlay struct {
+	// UpdateBelow keeps updating the Scenes below, after this one. I.e. for a dialog over a running game.
+	UpdateBelow bool
+
+	// RenderBelow keeps rendering the Scenes below (their `Renderer` Systems), before this one. I.e. for a
+	// translucent pause menu.
+	RenderBelow bool
+
+	// InputBelow lets the Scenes below see the keys, the mouse buttons and the scrolling once this one has
+	// been updated.
+	InputBelow bool
+}
+
+// EvictionPolicy decides when Scenes which are no longer in use get discarded. Discarding a Scene removes all of
+// its Systems (see Deinitializer) and unloads the resources it loaded through Files during Preload and Setup. It
+// is preloaded and set up again the next time it is used.
+type EvictionPolicy int
+
+const (
+	// KeepScenes keeps every Scene until UnregisterScene is called. This is the default.
+	KeepScenes EvictionPolicy = iota
+	// EvictHiddenScenes discards a Scene as soon as it is no longer in the stack of Scenes.
+	EvictHiddenScenes
+)
+
 type sceneWrapper struct {
 	scene   Scene
 	world   *World
 	mailbox *MessageManager
+
+	// urls are the resources loaded through Files during Preload and Setup
+	urls map[string]bool
+
+	// resized holds the window resizes which happened while the Scene was not in the stack
+	resized *WindowResizeMessage
+}
+
+func init() {
+	// forward the resizes to the Scenes, at once for the ones in the stack, and when shown again for the others
+	Subscribe(EngineMailbox, func(msg WindowResizeMessage) {
+		for _, wrapper := range scenes {
+			if wrapper.mailbox == nil {
+				continue
+			}
+			if inStack(wrapper) {
+				wrapper.mailbox.Dispatch(msg)
+				continue
+			}
+
+			if wrapper.resized == nil {
+				wrapper.resized = &msg
+			} else {
+				wrapper.resized.NewWidth, wrapper.resized.NewHeight = msg.NewWidth, msg.NewHeight
+			}
+		}
+	})
+}
+
+// show calls Show on the Scene, and tells it about the window resizes it missed.
+func show(wrapper *sceneWrapper) {
+	if shower, ok := wrapper.scene.(Shower); ok {
+		shower.Show()
+	}
+
+	if resized := wrapper.resized; resized != nil {
+		wrapper.resized = nil
+		wrapper.mailbox.Dispatch(*resized)
+	}
+}
+
+type sceneLayer struct {
+	wrapper *sceneWrapper
+	overlay Overlay
 }
 
 // CurrentScene returns the SceneWorld that is currently active
@@ -57,14 +134,72 @@ func CurrentScene() Scene {
 
 // SetScene sets the currentScene to the given Scene, and
 // optionally forcing to create a new World that goes with it.
+// Every Scene pushed with PushScene is discarded.
 func SetScene(s Scene, forceNewWorld bool) {
-	// Break down currentScene
+	// Break down currentScene, and the Scenes below it
+	for i := len(sceneStack) - 1; i >= 0; i-- {
+		if hider, ok := sceneStack[i].wrapper.scene.(Hider); ok {
+			hider.Hide()
+		}
+	}
+	sceneStack = nil
+	currentTransition = nil
+
+	pushScene(s, forceNewWorld, false, Overlay{})
+	evictHidden()
+}
+
+// PushScene makes the given Scene the currentScene, on top of the current one, which gets hidden until PopScene
+// is called. How the Scenes below keep being updated and rendered is controlled by the Overlay. It returns an
+// error if the Scene is already in the stack.
+func PushScene(s Scene, forceNewWorld bool, o Overlay) error {
+	for _, layer := range sceneStack {
+		if layer.wrapper.scene.Type() == s.Type() {
+			return fmt.Errorf("scene already pushed: %s", s.Type())
+		}
+	}
+
 	if currentScene != nil {
 		if hider, ok := currentScene.(Hider); ok {
 			hider.Hide()
 		}
 	}
 
+	pushScene(s, forceNewWorld, false, o)
+
+	return nil
+}
+
+// PopScene removes the currentScene pushed with PushScene, and shows the Scene below it again. It returns an
+// error if there is no Scene to go back to.
+func PopScene() error {
+	if len(sceneStack) < 2 {
+		return fmt.Errorf("no scene to pop")
+	}
+
+	if hider, ok := currentScene.(Hider); ok {
+		hider.Hide()
+	}
+
+	sceneStack = sceneStack[:len(sceneStack)-1]
+	activate(sceneStack[len(sceneStack)-1].wrapper)
+
+	show(sceneStack[len(sceneStack)-1].wrapper)
+	evictHidden()
+
+	return nil
+}
+
+// activate makes the Scene of the wrapper the current one, so its World and MessageManager are used by Systems.
+func activate(wrapper *sceneWrapper) {
+	currentScene = wrapper.scene
+	currentWorld = wrapper.world
+	Mailbox = wrapper.mailbox
+}
+
+// pushScene puts the Scene on top of the stack, setting it up if needed. Preload is left out when preloaded, as
+// TransitionTo called it already.
+func pushScene(s Scene, forceNewWorld, preloaded bool, o Overlay) {
 	// Register Scene if needed
 	wrapper, registered := scenes[s.Type()]
 	if !registered {
@@ -76,29 +211,96 @@ func SetScene(s Scene, forceNewWorld bool) {
 	var doSetup bool
 
 	if wrapper.world == nil || forceNewWorld {
-		wrapper.world = &World{}
+		if wrapper.world != nil {
+			// release what the previous World loaded, before preloading again
+			discard(wrapper)
+		}
 		wrapper.mailbox = &MessageManager{}
+		wrapper.world = &World{mailbox: wrapper.mailbox}
+		wrapper.resized = nil
 
 		doSetup = true
 	}
 
 	// Do the switch
-	currentScene = s
-	currentWorld = wrapper.world
-	Mailbox = wrapper.mailbox
+	sceneStack = append(sceneStack, sceneLayer{wrapper: wrapper, overlay: o})
+	activate(wrapper)
 
 	// doSetup is true whenever we're (re)initializing the Scene
 	if doSetup {
-		s.Preload()
-
-		wrapper.mailbox.listeners = make(map[string][]MessageHandler)
-
+		// a Scene may push another one from its Setup
+		previous := setOwner(wrapper)
+		if !preloaded {
+			s.Preload()
+		}
 		s.Setup(wrapper.world)
+		setOwner(previous)
 	} else {
-		if shower, ok := currentScene.(Shower); ok {
-			shower.Show()
+		show(wrapper)
+	}
+}
+
+// updateScenes updates the Scenes of the stack for one frame. The Scenes which keep updating are updated from the
+// top, so the currentScene gets the input first, and the messages posted to the Scenes of the stack which are not
+// updated are delivered nonetheless. When several Scenes are rendered, their Renderers are left out,
+// and run once every Scene got updated, from the bottom, so the currentScene is drawn last.
+func updateScenes(dt float32) {
+	if Input != nil {
+		dt = Input.inputFrame(dt)
+		Input.updateSequences()
+	}
+	updateTransition(dt)
+	EngineMailbox.Flush()
+
+	steps := fixedSteps(dt)
+	top := len(sceneStack) - 1
+	bottom := top
+	for bottom > 0 && sceneStack[bottom].overlay.RenderBelow {
+		bottom--
+	}
+
+	clearFrame()
+
+	updated := true
+	for i := top; i >= 0; i-- {
+		if i < top {
+			above := sceneStack[i+1].overlay
+			updated = updated && above.UpdateBelow
+			if !above.InputBelow && Input != nil {
+				Input.setBlocked(true)
+			}
+		}
+
+		// the posted messages are delivered to every Scene of the stack, even when it is not updated
+		activate(sceneStack[i].wrapper)
+		Mailbox.Flush()
+		if !updated {
+			continue
+		}
+		for n := 0; n < steps; n++ {
+			currentWorld.FixedUpdate(opts.FixedTimeStep)
+		}
+		if i == top && bottom == top {
+			// the only Scene rendered, its Systems run by priority
+			currentWorld.update(dt, allSystems)
+		} else {
+			currentWorld.update(dt, updatePass)
 		}
 	}
+	if Input != nil {
+		Input.setBlocked(false)
+	}
+
+	if bottom == top {
+		if top >= 0 {
+			activate(sceneStack[top].wrapper)
+		}
+		return
+	}
+	for i := bottom; i <= top; i++ {
+		activate(sceneStack[i].wrapper)
+		currentWorld.update(dt, renderPass)
+	}
 }
 
 // RegisterScene registers the `Scene`, so it can later be used by `SetSceneByName`
@@ -109,6 +311,65 @@ func RegisterScene(s Scene) {
 	}
 }
 
+// UnregisterScene discards the Scene registered with the given name, see EvictionPolicy, and forgets about it. It
+// returns an error if the Scene is not registered, or still in use.
+func UnregisterScene(name string) error {
+	wrapper, ok := scenes[name]
+	if !ok {
+		return fmt.Errorf("scene not registered: %s", name)
+	}
+	if inUse(wrapper) {
+		return fmt.Errorf("scene in use: %s", name)
+	}
+
+	delete(scenes, name)
+	return discard(wrapper)
+}
+
+// inUse returns whether the Scene is in the stack, or about to be by TransitionTo.
+func inUse(wrapper *sceneWrapper) bool {
+	return inStack(wrapper) || currentTransition != nil && currentTransition.scene.Type() == wrapper.scene.Type()
+}
+
+// inStack returns whether the Scene is in the stack.
+func inStack(wrapper *sceneWrapper) bool {
+	for _, layer := range sceneStack {
+		if layer.wrapper == wrapper {
+			return true
+		}
+	}
+	return false
+}
+
+// discard removes the Systems of the Scene, so they can release their entities, and unloads its resources.
+func discard(wrapper *sceneWrapper) error {
+	if wrapper.world != nil {
+		systems := wrapper.world.Systems()
+		for i := len(systems) - 1; i >= 0; i-- {
+			wrapper.world.RemoveSystem(systems[i])
+		}
+	}
+	wrapper.world = nil
+	wrapper.mailbox = nil
+
+	return release(wrapper)
+}
+
+// evictHidden discards the Scenes which are no longer in use, if the EvictionPolicy says so.
+func evictHidden() {
+	if opts.SceneEviction != EvictHiddenScenes {
+		return
+	}
+
+	for _, wrapper := range scenes {
+		if wrapper.world != nil && !inUse(wrapper) {
+			if err := discard(wrapper); err != nil {
+				log.Println("[WARNING] unable to discard scene:", err)
+			}
+		}
+	}
+}
+
 // SetSceneByName does a lookup for the `Scene` where its `Type()` equals `name`, and then sets it as current `Scene`
 func SetSceneByName(name string, forceNewWorld bool) error {
 	scene, ok := scenes[name]
@@ -0,0 +1,87 @@
+//+build headless
+
+package minieng
+
+import "testing"
+
+// stackTestScene logs when it gets hidden and shown
+type stackTestScene struct {
+	testScene
+	log *[]string
+}
+
+func (s *stackTestScene) Hide() { *s.log = append(*s.log, "hide "+s.name) }
+func (s *stackTestScene) Show() { *s.log = append(*s.log, "show "+s.name) }
+
+// resetScenes forgets about the Scenes registered by a test
+func resetScenes() {
+	scenes = make(map[string]*sceneWrapper)
+	sceneStack = nil
+	currentTransition = nil
+	currentScene, currentWorld, Mailbox = nil, nil, nil
+}
+
+func TestPushPopScene(t *testing.T) {
+	defer resetScenes()
+
+	var log []string
+	game := &stackTestScene{testScene: testScene{name: "game"}, log: &log}
+	menu := &stackTestScene{testScene: testScene{name: "menu"}, log: &log}
+
+	SetScene(game, false)
+	if err := PushScene(menu, false, Overlay{}); err != nil {
+		t.Fatalf("PushScene: %v", err)
+	}
+	if CurrentScene() != menu {
+		t.Errorf("CurrentScene() = %v after PushScene, want the menu", CurrentScene())
+	}
+	if err := PushScene(menu, false, Overlay{}); err == nil {
+		t.Error("pushing a Scene already in the stack succeeded")
+	}
+
+	if err := PopScene(); err != nil {
+		t.Fatalf("PopScene: %v", err)
+	}
+	if CurrentScene() != game {
+		t.Errorf("CurrentScene() = %v after PopScene, want the game", CurrentScene())
+	}
+	if err := PopScene(); err == nil {
+		t.Error("popping the last Scene succeeded")
+	}
+
+	want := []string{"hide game", "hide menu", "show game"}
+	if len(log) != len(want) {
+		t.Fatalf("log = %v, want %v", log, want)
+	}
+	for i := range want {
+		if log[i] != want[i] {
+			t.Errorf("log = %v, want %v", log, want)
+			break
+		}
+	}
+}
+
+func TestOverlayUpdateBelow(t *testing.T) {
+	defer resetScenes()
+	defer func(input *InputManager) { Input = input }(Input)
+	// the Scenes are updated without any input
+	Input = nil
+
+	for _, o := range []Overlay{{}, {UpdateBelow: true}} {
+		var updated []string
+		logger := func(name string) System {
+			return &funcSystem{func(float32) { updated = append(updated, name) }}
+		}
+		SetScene(&testScene{name: "below", systems: []System{logger("below")}}, true)
+		PushScene(&testScene{name: "above", systems: []System{logger("above")}}, true, o)
+
+		updateScenes(1)
+		want := []string{"above"}
+		if o.UpdateBelow {
+			want = append(want, "below")
+		}
+		if len(updated) != len(want) || updated[0] != want[0] || len(want) > 1 && updated[1] != want[1] {
+			t.Errorf("with %+v, updated %v, want %v", o, updated, want)
+		}
+	}
+}
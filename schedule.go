@@ -0,0 +1,128 @@
+package minieng
+
+import (
+	"reflect"
+	"sync"
+)
+
+// schedule splits the Systems, in execution order, into batches. A batch is
+// either a single System which has to run on the main thread, or consecutive
+// Concurrent Systems which do not conflict with each other.
+func schedule(sorted []*systemEntry) [][]*systemEntry {
+	var batches [][]*systemEntry
+
+	for _, entry := range sorted {
+		if _, ok := entry.system.(Concurrent); ok && len(batches) > 0 {
+			last := batches[len(batches)-1]
+			if canJoin(last, entry.system) {
+				batches[len(batches)-1] = append(last, entry)
+				continue
+			}
+		}
+		batches = append(batches, []*systemEntry{entry})
+	}
+
+	return batches
+}
+
+// canJoin returns whether the Concurrent System can run together with the
+// Systems of the batch.
+func canJoin(batch []*systemEntry, system System) bool {
+	for _, other := range batch {
+		if _, ok := other.system.(Concurrent); !ok {
+			return false
+		}
+		if conflicts(other.system, system) {
+			return false
+		}
+	}
+	return true
+}
+
+// conflicts returns whether the two Concurrent Systems cannot run at the same
+// time.
+func conflicts(a, b System) bool {
+	ca, cb := a.(Concurrent), b.(Concurrent)
+
+	if overlaps(ca.Writes(), cb.Writes()) || overlaps(ca.Writes(), cb.Reads()) || overlaps(ca.Reads(), cb.Writes()) {
+		return true
+	}
+
+	return dependsOn(a, b) || dependsOn(b, a)
+}
+
+// dependsOn returns whether a declared it runs before or after b.
+func dependsOn(a, b System) bool {
+	var declared []System
+	if runsAfter, ok := a.(RunsAfter); ok {
+		declared = append(declared, runsAfter.RunsAfter()...)
+	}
+	if runsBefore, ok := a.(RunsBefore); ok {
+		declared = append(declared, runsBefore.RunsBefore()...)
+	}
+
+	for _, t := range declared {
+		if reflect.TypeOf(t) == reflect.TypeOf(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns whether the two lists have a component type in common.
+func overlaps(a, b []interface{}) bool {
+	for _, ta := range a {
+		for _, tb := range b {
+			if reflect.TypeOf(ta) == reflect.TypeOf(tb) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run invokes fn for every System which is not paused, batch after batch. The
+// Systems of a batch of Concurrent Systems each get their own goroutine, the
+// others are invoked on the calling thread. The Systems removed meanwhile are
+// skipped. A panic in a Concurrent System is raised again on the calling
+// thread once its batch is done.
+func (w *World) run(fn func(System)) {
+	for _, batch := range w.batches {
+		if len(batch) == 1 {
+			if entry := batch[0]; !entry.paused && !entry.removed {
+				fn(entry.system)
+			}
+			continue
+		}
+
+		var (
+			wg        sync.WaitGroup
+			panicLock sync.Mutex
+			panicked  interface{}
+		)
+		for _, entry := range batch {
+			if entry.paused || entry.removed {
+				continue
+			}
+			wg.Add(1)
+			go func(system System) {
+				defer wg.Done()
+				// a panic can't be recovered from another goroutine, hand it to the calling one
+				defer func() {
+					if r := recover(); r != nil {
+						panicLock.Lock()
+						if panicked == nil {
+							panicked = r
+						}
+						panicLock.Unlock()
+					}
+				}()
+				fn(system)
+			}(entry.system)
+		}
+		wg.Wait()
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
+}
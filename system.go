@@ -1,5 +1,12 @@
 package minieng
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
 // A System implements logic for processing entities possessing components of
 // the same aspects as the system. A System should iterate over its Entities on
 // `Update`, in any way suitable for the current implementation.
@@ -17,6 +24,14 @@ type System interface {
 	Remove(e BasicEntity)
 }
 
+// FixedUpdater is an optional interface a System can implement to be updated at a fixed rate, as configured by
+// `RunOptions.FixedTimeStep`.
+type FixedUpdater interface {
+	// FixedUpdate is invoked by the engine zero or more times per frame, always with the same dt. It is
+	// invoked before `Update` of the same frame.
+	FixedUpdate(dt float32)
+}
+
 // Prioritizer specifies the priority of systems.
 type Prioritizer interface {
 	// Priority indicates the order in which Systems should be executed per
@@ -24,6 +39,53 @@ type Prioritizer interface {
 	Priority() int
 }
 
+// RunsAfter is an optional interface a System can implement to declare that
+// it has to be updated after other Systems, regardless of their priorities.
+type RunsAfter interface {
+	// RunsAfter returns a value of the type of each System this one has to be
+	// updated after, i.e. `(*common.MouseSystem)(nil)`. Types which are not in
+	// the World are ignored.
+	RunsAfter() []System
+}
+
+// RunsBefore is an optional interface a System can implement to declare that
+// it has to be updated before other Systems, regardless of their priorities.
+type RunsBefore interface {
+	// RunsBefore returns a value of the type of each System this one has to be
+	// updated before, i.e. `(*common.RenderSystem)(nil)`. Types which are not in
+	// the World are ignored.
+	RunsBefore() []System
+}
+
+// Concurrent is an optional interface a System can implement to be updated
+// on its own goroutine, concurrently with the other Concurrent Systems it does
+// not conflict with. Implementing it flags the System as not needing the main
+// (GL) thread: its Update must not make any GL call, nor dispatch messages or
+// pause Systems.
+//
+// Two Concurrent Systems conflict whenever one writes a component type the
+// other reads or writes, or whenever one declared it runs before or after the
+// other.
+type Concurrent interface {
+	// Reads returns a value of each component type the System reads, i.e.
+	// `(*common.SpaceComponent)(nil)`.
+	Reads() []interface{}
+
+	// Writes returns a value of each component type the System writes, i.e.
+	// `(*common.MouseComponent)(nil)`.
+	Writes() []interface{}
+}
+
+// Renderer is an optional interface a System can implement to indicate it
+// draws the World instead of updating it. Renderers keep running for Scenes
+// which are only rendered below an Overlay (see PushScene). While several
+// Scenes are rendered, the Renderers of each one run after all the Scenes got
+// updated; otherwise they run by priority like the other Systems.
+type Renderer interface {
+	// Renderer is a marker method, it is never called.
+	Renderer()
+}
+
 // Initializer provides initialization of systems.
 type Initializer interface {
 	// New initializes the given System, and may be used to initialize some
@@ -31,6 +93,13 @@ type Initializer interface {
 	New(*World)
 }
 
+// Deinitializer provides teardown of systems.
+type Deinitializer interface {
+	// Deinit is called when the System gets removed from the World through
+	// `World.RemoveSystem`, and may be used to release what New acquired.
+	Deinit(*World)
+}
+
 // systems implements a sortable list of `System`. It is indexed on
 // `System.Priority()`.
 type systems []System
@@ -55,3 +124,109 @@ func (s systems) Less(i, j int) bool {
 func (s systems) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
+
+// sortSystems returns the execution order of the given Systems, which are in
+// insertion order, as indices into registered: by priority, then by insertion,
+// while respecting RunsAfter and RunsBefore. It returns an error if the
+// dependencies are cyclic.
+func sortSystems(registered systems) ([]int, error) {
+	// positions[i] is the index in registered of the i-th System by priority
+	positions := make([]int, len(registered))
+	for i := range positions {
+		positions[i] = i
+	}
+	sort.SliceStable(positions, func(i, j int) bool {
+		return registered.Less(positions[i], positions[j])
+	})
+	ordered := make(systems, len(registered))
+	for i, position := range positions {
+		ordered[i] = registered[position]
+	}
+
+	// after[i] lists the indices in ordered of the Systems i runs after
+	after := make([][]int, len(ordered))
+	indicesOf := func(t System) []int {
+		var indices []int
+		for i, sys := range ordered {
+			if reflect.TypeOf(sys) == reflect.TypeOf(t) {
+				indices = append(indices, i)
+			}
+		}
+		return indices
+	}
+	for i, sys := range ordered {
+		if runsAfter, ok := sys.(RunsAfter); ok {
+			for _, t := range runsAfter.RunsAfter() {
+				after[i] = append(after[i], indicesOf(t)...)
+			}
+		}
+		if runsBefore, ok := sys.(RunsBefore); ok {
+			for _, t := range runsBefore.RunsBefore() {
+				for _, j := range indicesOf(t) {
+					after[j] = append(after[j], i)
+				}
+			}
+		}
+	}
+
+	// repeatedly pick the first System whose dependencies all ran already
+	sorted := make([]int, 0, len(ordered))
+	done := make([]bool, len(ordered))
+	ready := func(i int) bool {
+		for _, j := range after[i] {
+			if !done[j] {
+				return false
+			}
+		}
+		return true
+	}
+	for len(sorted) < len(ordered) {
+		next := -1
+		for i := range ordered {
+			if !done[i] && ready(i) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("cyclic system dependencies: %s", describeCycle(ordered, after, done))
+		}
+		done[next] = true
+		sorted = append(sorted, positions[next])
+	}
+	return sorted, nil
+}
+
+// describeCycle walks the dependencies of the Systems which could not be sorted
+// until it finds a cycle, and returns it as "*A -> *B -> *A".
+func describeCycle(ordered systems, after [][]int, done []bool) string {
+	start := 0
+	for done[start] {
+		start++
+	}
+
+	// every System left has at least one dependency left, so this terminates
+	visited := make(map[int]int)
+	var path []int
+	for i := start; ; {
+		if at, ok := visited[i]; ok {
+			path = append(path[at:], i)
+			break
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+		for _, j := range after[i] {
+			if !done[j] {
+				i = j
+				break
+			}
+		}
+	}
+
+	// path lists each System followed by one it runs after, print it in execution order
+	names := make([]string, len(path))
+	for k, i := range path {
+		names[len(path)-1-k] = fmt.Sprintf("%T", ordered[i])
+	}
+	return strings.Join(names, " -> ")
+}
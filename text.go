@@ -0,0 +1,88 @@
+package minieng
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TextInputMessage is a message that's being dispatched whenever the user types text, i.e. a character or a word
+// committed by an input method. Control characters, such as Enter or Backspace, are only reported as keys. It is
+// posted to EngineMailbox.
+type TextInputMessage struct {
+	Text string
+}
+
+// Type returns the type of the current object "TextInputMessage"
+func (TextInputMessage) Type() string { return "TextInputMessage" }
+
+// TextCompositionMessage is a message that's being dispatched whenever the text being composed with an input method
+// changes, before it gets committed as a TextInputMessage. Text is empty once the composition is over. It is posted
+// to EngineMailbox.
+type TextCompositionMessage struct {
+	Text string
+}
+
+// Type returns the type of the current object "TextCompositionMessage"
+func (TextCompositionMessage) Type() string { return "TextCompositionMessage" }
+
+// Text returns the text typed during the current frame, see TextInputMessage.
+func (im *InputManager) Text() string {
+	if im.blocked {
+		return ""
+	}
+	return im.text
+}
+
+// Composition returns the text being composed with an input method, see TextCompositionMessage.
+func (im *InputManager) Composition() string {
+	if im.blocked {
+		return ""
+	}
+	return im.composition
+}
+
+// typeText records text typed by the user, as reported by the backend.
+func (im *InputManager) typeText(text string) {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+	if text == "" || im.replay != nil {
+		return
+	}
+
+	if im.recorder != nil {
+		im.recorder.frame.Text += text
+	}
+	im.addText(text)
+}
+
+// addText adds text to the text typed during the current frame, it is also used to replay the input.
+func (im *InputManager) addText(text string) {
+	if text == "" {
+		return
+	}
+
+	im.text += text
+	EngineMailbox.Post(TextInputMessage{Text: text})
+}
+
+// compose records the text being composed with an input method, as reported by the backend.
+func (im *InputManager) compose(text string) {
+	if im.replay != nil {
+		return
+	}
+	im.setComposition(text)
+}
+
+// setComposition updates the text being composed, it is also used to replay the input.
+func (im *InputManager) setComposition(text string) {
+	if text == im.composition {
+		return
+	}
+
+	im.composition = text
+	EngineMailbox.Post(TextCompositionMessage{Text: text})
+}
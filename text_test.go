@@ -0,0 +1,49 @@
+//+build headless
+
+package minieng
+
+import "testing"
+
+func TestTypeText(t *testing.T) {
+	var typed, composed []string
+	subs := []*Subscription{
+		Subscribe(EngineMailbox, func(msg TextInputMessage) { typed = append(typed, msg.Text) }),
+		Subscribe(EngineMailbox, func(msg TextCompositionMessage) { composed = append(composed, msg.Text) }),
+	}
+	defer func() {
+		for _, sub := range subs {
+			sub.Cancel()
+		}
+	}()
+	im := NewInputManager()
+
+	im.compose("ni")
+	im.compose("ni")
+	im.compose("")
+	// the control characters are only keys
+	im.typeText("\b")
+	im.typeText("你")
+	im.typeText("a\tb")
+	if text := im.Text(); text != "你ab" {
+		t.Errorf("Text() = %q, want %q", text, "你ab")
+	}
+
+	im.setBlocked(true)
+	if text := im.Text(); text != "" {
+		t.Errorf("Text() = %q while blocked, want nothing", text)
+	}
+	im.setBlocked(false)
+
+	EngineMailbox.Flush()
+	if len(typed) != 2 || typed[0] != "你" || typed[1] != "ab" {
+		t.Errorf("TextInputMessages %q, want the two texts typed", typed)
+	}
+	if len(composed) != 2 || composed[0] != "ni" || composed[1] != "" {
+		t.Errorf("TextCompositionMessages %q, want the composition then its end", composed)
+	}
+
+	im.update()
+	if text := im.Text(); text != "" {
+		t.Errorf("Text() = %q the next frame, want nothing", text)
+	}
+}
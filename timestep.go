@@ -0,0 +1,75 @@
+package minieng
+
+import (
+	"math"
+)
+
+const (
+	// defaultMaxCatchUpSteps is used whenever `RunOptions.MaxCatchUpSteps` is not set
+	defaultMaxCatchUpSteps = 5
+
+	// defaultTargetFPS is used whenever `RunOptions.TargetFPS` is not set
+	defaultTargetFPS = 30
+
+	// defaultIdleAfter is used whenever `RunOptions.IdleAfter` is not set
+	defaultIdleAfter = 2
+)
+
+var (
+	// accumulator holds the time which has not been consumed by fixed steps yet
+	accumulator float32
+
+	// alpha is the interpolation factor between the last two fixed steps
+	alpha float32
+)
+
+// InterpolationAlpha returns how far (between 0 and 1) the current frame is between the previous and the next
+// fixed step. Rendering Systems can use it to interpolate between the last two simulated states. It is always 0
+// when `RunOptions.FixedTimeStep` is not set.
+func InterpolationAlpha() float32 {
+	return alpha
+}
+
+// fixedSteps returns how many fixed steps the elapsed time dt accounts for, if enabled, and updates the
+// interpolation alpha accordingly.
+func fixedSteps(dt float32) int {
+	step := opts.FixedTimeStep
+	if step <= 0 {
+		return 0
+	}
+
+	maxSteps := opts.MaxCatchUpSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxCatchUpSteps
+	}
+
+	accumulator += dt
+	steps := 0
+	for ; accumulator >= step; steps++ {
+		if steps == maxSteps {
+			// we're too far behind, drop the time we could not simulate
+			accumulator = float32(math.Mod(float64(accumulator), float64(step)))
+			break
+		}
+		accumulator -= step
+	}
+	alpha = accumulator / step
+
+	return steps
+}
+
+// targetFPS returns the framerate the main loop should be throttled to
+func targetFPS() int {
+	if opts.TargetFPS > 0 {
+		return opts.TargetFPS
+	}
+	return defaultTargetFPS
+}
+
+// idleAfter returns the amount of seconds without input before entering the idle mode
+func idleAfter() float64 {
+	if opts.IdleAfter > 0 {
+		return float64(opts.IdleAfter)
+	}
+	return defaultIdleAfter
+}
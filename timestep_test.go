@@ -0,0 +1,69 @@
+//+build headless
+
+package minieng
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFixedSteps(t *testing.T) {
+	defer func(o RunOptions) { opts, accumulator, alpha = o, 0, 0 }(opts)
+	opts = RunOptions{FixedTimeStep: 1.0 / 64, MaxCatchUpSteps: 4}
+	accumulator = 0
+
+	for _, frame := range []struct {
+		dt    float32
+		steps int
+		alpha float32
+	}{
+		{dt: 1.0 / 128, steps: 0, alpha: 0.5},
+		{dt: 1.0 / 128, steps: 1, alpha: 0},
+		{dt: 5.0 / 128, steps: 2, alpha: 0.5},
+		{dt: 1.0 / 128, steps: 1, alpha: 0},
+		// too far behind, the steps beyond MaxCatchUpSteps are dropped
+		{dt: 21.0 / 128, steps: 4, alpha: 0.5},
+		{dt: 0, steps: 0, alpha: 0.5},
+	} {
+		if steps := fixedSteps(frame.dt); steps != frame.steps {
+			t.Errorf("fixedSteps(%v) = %d, want %d", frame.dt, steps, frame.steps)
+		}
+		if a := InterpolationAlpha(); math.Abs(float64(a-frame.alpha)) > 1e-6 {
+			t.Errorf("alpha after fixedSteps(%v) = %v, want %v", frame.dt, a, frame.alpha)
+		}
+	}
+}
+
+// fixedTestSystem counts its fixed updates
+type fixedTestSystem struct {
+	steps  int
+	dt     float32
+	frames int
+}
+
+func (s *fixedTestSystem) Update(float32)         { s.frames++ }
+func (s *fixedTestSystem) Remove(BasicEntity)     {}
+func (s *fixedTestSystem) FixedUpdate(dt float32) { s.steps++; s.dt = dt }
+
+func TestFixedUpdateHeadless(t *testing.T) {
+	defer func(o RunOptions) { opts, accumulator, alpha = o, 0, 0 }(opts)
+	opts = RunOptions{FixedTimeStep: 1.0 / 64}
+	accumulator = 0
+
+	system := &fixedTestSystem{}
+	RunPreparation(&testScene{name: "fixedUpdateHeadless", systems: []System{system}})
+	Time.Tick()
+	for i := 0; i < 9; i++ {
+		Time.Advance(5 * time.Second / 128)
+		RunIteration()
+	}
+
+	// 9 frames of 5/128s are 22 steps of 1/64s, with half a step left
+	if s := system; s.frames != 9 || s.steps != 22 || s.dt != 1.0/64 {
+		t.Errorf("%d frames, %d fixed steps of %v, want 9 frames and 22 steps of %v", s.frames, s.steps, s.dt, 1.0/64)
+	}
+	if a := InterpolationAlpha(); math.Abs(float64(a-0.5)) > 1e-3 {
+		t.Errorf("alpha = %v, want 0.5", a)
+	}
+}
@@ -0,0 +1,226 @@
+package minieng
+
+import (
+	"math"
+	"sort"
+)
+
+// TouchPhase is the stage of a Touch in the current frame.
+type TouchPhase int
+
+const (
+	// TouchBegin is the phase of a Touch which just started
+	TouchBegin TouchPhase = iota
+	// TouchMove is the phase of a Touch which moved since the last frame
+	TouchMove
+	// TouchStationary is the phase of a Touch which didn't move since the last frame
+	TouchStationary
+	// TouchEnd is the phase of a Touch which was just lifted
+	TouchEnd
+	// TouchCancel is the phase of a Touch which was interrupted by the system
+	TouchCancel
+)
+
+var (
+	// TapMaxDuration is the number of seconds a Touch may last to be a tap
+	TapMaxDuration float32 = 0.3
+
+	// LongPressDuration is the number of seconds a Touch has to last to be a long press
+	LongPressDuration float32 = 0.5
+
+	// TapSlop is the distance, in pixels, a Touch may move and still be a tap or a long press
+	TapSlop float32 = 10
+)
+
+// Touch is a finger on the screen.
+type Touch struct {
+	ID    int64
+	X, Y  float32
+	Phase TouchPhase
+
+	// StartX and StartY are where the Touch began, StartTime when, see Clock.Time
+	StartX, StartY float32
+	StartTime      float32
+
+	// prevX and prevY are the position during the previous frame
+	prevX, prevY float32
+	// moved is true once the Touch went further than TapSlop
+	moved bool
+	// multi is true when another Touch happened meanwhile
+	multi       bool
+	longPressed bool
+}
+
+// touch records a touch event reported by the backend.
+func (im *InputManager) touch(id int64, phase TouchPhase, x, y float32) {
+	if im.replay != nil {
+		return
+	}
+	if im.recorder != nil {
+		im.recorder.frame.Touches = append(im.recorder.frame.Touches, touchEvent{ID: id, Phase: phase, X: x, Y: y})
+	}
+	im.applyTouch(id, phase, x, y)
+}
+
+// applyTouch updates the Touches, it is also used to replay the input.
+func (im *InputManager) applyTouch(id int64, phase TouchPhase, x, y float32) {
+	if im.Touches == nil {
+		im.Touches = make(map[int64]Touch)
+	}
+
+	if phase == TouchBegin {
+		t := Touch{ID: id, X: x, Y: y, Phase: TouchBegin, StartX: x, StartY: y, StartTime: clockTime(), prevX: x, prevY: y}
+		for other, o := range im.Touches {
+			if o.Phase != TouchEnd && o.Phase != TouchCancel {
+				o.multi = true
+				im.Touches[other] = o
+				t.multi = true
+			}
+		}
+		if !t.multi {
+			im.primaryTouch = id
+		}
+		im.Touches[id] = t
+		return
+	}
+
+	t, ok := im.Touches[id]
+	if !ok {
+		return
+	}
+	t.X, t.Y = x, y
+	if math.Hypot(float64(x-t.StartX), float64(y-t.StartY)) > float64(TapSlop) {
+		t.moved = true
+	}
+
+	switch {
+	case phase != TouchMove:
+		t.Phase = phase
+	case t.Phase != TouchBegin:
+		t.Phase = TouchMove
+	}
+	im.Touches[id] = t
+}
+
+// cancelTouches interrupts the Touches which are still on the screen, i.e. when the app loses the focus and won't
+// see them end. The mouse following the first finger is released.
+func (im *InputManager) cancelTouches() {
+	for id, t := range im.Touches {
+		if t.Phase == TouchEnd || t.Phase == TouchCancel {
+			continue
+		}
+		if id == im.primaryTouch {
+			im.mouseButton(MouseButtonLeft, Release, 0)
+		}
+		im.touch(id, TouchCancel, t.X, t.Y)
+	}
+}
+
+// isPrimaryTouch returns whether the Touch is the first finger put on the screen, the one the mouse follows.
+func (im *InputManager) isPrimaryTouch(id int64) bool {
+	_, ok := im.Touches[id]
+	return ok && im.primaryTouch == id
+}
+
+// updateTouches recognizes the gestures and forgets the Touches which ended. It is invoked once every frame.
+func (im *InputManager) updateTouches() {
+	if len(im.Touches) == 0 {
+		return
+	}
+	now := clockTime()
+
+	ids := make([]int64, 0, len(im.Touches))
+	for id := range im.Touches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var active []Touch
+	for _, id := range ids {
+		t := im.Touches[id]
+		steady := !t.moved && !t.multi && !t.longPressed
+
+		switch t.Phase {
+		case TouchEnd:
+			if steady && now-t.StartTime <= TapMaxDuration {
+				EngineMailbox.Post(TapMessage{X: t.X, Y: t.Y})
+			}
+			fallthrough
+		case TouchCancel:
+			delete(im.Touches, id)
+			continue
+		}
+
+		if steady && now-t.StartTime >= LongPressDuration {
+			t.longPressed = true
+			EngineMailbox.Post(LongPressMessage{X: t.X, Y: t.Y})
+		}
+		active = append(active, t)
+	}
+
+	if len(active) == 2 {
+		a, b := active[0], active[1]
+		prev := math.Hypot(float64(a.prevX-b.prevX), float64(a.prevY-b.prevY))
+		dist := math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+		x, y := (a.X+b.X)/2, (a.Y+b.Y)/2
+
+		if prev > 0 && dist != prev {
+			EngineMailbox.Post(PinchMessage{Scale: float32(dist / prev), X: x, Y: y})
+		}
+		if dx, dy := x-(a.prevX+b.prevX)/2, y-(a.prevY+b.prevY)/2; dx != 0 || dy != 0 {
+			EngineMailbox.Post(TwoFingerPanMessage{DX: dx, DY: dy, X: x, Y: y})
+		}
+	}
+
+	for _, t := range active {
+		t.prevX, t.prevY = t.X, t.Y
+		t.Phase = TouchStationary
+		im.Touches[t.ID] = t
+	}
+}
+
+// clockTime returns the time of the engine clock, or 0 before it is started.
+func clockTime() float32 {
+	if Time == nil {
+		return 0
+	}
+	return Time.Time()
+}
+
+// TapMessage is a message that's being dispatched whenever a finger briefly touches the screen without moving. It
+// is posted to EngineMailbox, like the other gestures.
+type TapMessage struct {
+	X, Y float32
+}
+
+// Type returns the type of the current object "TapMessage"
+func (TapMessage) Type() string { return "TapMessage" }
+
+// LongPressMessage is a message that's being dispatched whenever a finger stays on the screen without moving for
+// LongPressDuration.
+type LongPressMessage struct {
+	X, Y float32
+}
+
+// Type returns the type of the current object "LongPressMessage"
+func (LongPressMessage) Type() string { return "LongPressMessage" }
+
+// PinchMessage is a message that's being dispatched whenever two fingers on the screen get closer or further apart.
+// Scale is the ratio between the current and the previous distance between them, X and Y the point between them.
+type PinchMessage struct {
+	Scale float32
+	X, Y  float32
+}
+
+// Type returns the type of the current object "PinchMessage"
+func (PinchMessage) Type() string { return "PinchMessage" }
+
+// TwoFingerPanMessage is a message that's being dispatched whenever two fingers move on the screen. DX and DY are
+// the movement of the point between them since the previous frame, X and Y its position.
+type TwoFingerPanMessage struct {
+	DX, DY float32
+	X, Y   float32
+}
+
+// Type returns the type of the current object "TwoFingerPanMessage"
+func (TwoFingerPanMessage) Type() string { return "TwoFingerPanMessage" }
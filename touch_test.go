@@ -0,0 +1,125 @@
+//+build headless
+
+package minieng
+
+import (
+	"testing"
+	"time"
+)
+
+// gestures records the gestures posted to EngineMailbox
+type gestures struct {
+	taps, longPresses int
+	pinches           []float32
+	pans              [][2]float32
+	subscriptions     []*Subscription
+}
+
+func listenGestures() *gestures {
+	g := &gestures{}
+	g.subscriptions = []*Subscription{
+		Subscribe(EngineMailbox, func(TapMessage) { g.taps++ }),
+		Subscribe(EngineMailbox, func(LongPressMessage) { g.longPresses++ }),
+		Subscribe(EngineMailbox, func(msg PinchMessage) { g.pinches = append(g.pinches, msg.Scale) }),
+		Subscribe(EngineMailbox, func(msg TwoFingerPanMessage) { g.pans = append(g.pans, [2]float32{msg.DX, msg.DY}) }),
+	}
+	return g
+}
+
+func (g *gestures) cancel() {
+	for _, sub := range g.subscriptions {
+		sub.Cancel()
+	}
+}
+
+// touchFrame ends a frame after the given delay, and delivers the gestures
+func touchFrame(im *InputManager, delay time.Duration) {
+	Time.Advance(delay)
+	Time.Tick()
+	im.update()
+	EngineMailbox.Flush()
+}
+
+func TestTapAndLongPress(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	g := listenGestures()
+	defer g.cancel()
+	im := NewInputManager()
+
+	im.touch(1, TouchBegin, 10, 10)
+	touchFrame(im, 0)
+	im.touch(1, TouchEnd, 12, 10)
+	touchFrame(im, 100*time.Millisecond)
+	if g.taps != 1 || g.longPresses != 0 {
+		t.Errorf("%d taps and %d long presses for a short touch, want 1 tap", g.taps, g.longPresses)
+	}
+	if len(im.Touches) != 0 {
+		t.Errorf("%d touches left once lifted", len(im.Touches))
+	}
+
+	im.touch(2, TouchBegin, 10, 10)
+	touchFrame(im, 0)
+	touchFrame(im, 600*time.Millisecond)
+	touchFrame(im, 100*time.Millisecond)
+	im.touch(2, TouchEnd, 10, 10)
+	touchFrame(im, 0)
+	if g.taps != 1 || g.longPresses != 1 {
+		t.Errorf("%d taps and %d long presses after a long touch, want 1 of each", g.taps, g.longPresses)
+	}
+
+	// moving further than TapSlop is neither
+	im.touch(3, TouchBegin, 10, 10)
+	im.touch(3, TouchMove, 50, 10)
+	touchFrame(im, 0)
+	im.touch(3, TouchEnd, 50, 10)
+	touchFrame(im, 0)
+	if g.taps != 1 {
+		t.Errorf("a touch which moved is a tap")
+	}
+}
+
+func TestPinchAndPan(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	g := listenGestures()
+	defer g.cancel()
+	im := NewInputManager()
+
+	im.touch(1, TouchBegin, 0, 0)
+	im.touch(2, TouchBegin, 10, 0)
+	touchFrame(im, 0)
+	im.touch(2, TouchMove, 20, 0)
+	touchFrame(im, 0)
+	im.touch(1, TouchMove, 10, 10)
+	im.touch(2, TouchMove, 30, 10)
+	touchFrame(im, 0)
+
+	if len(g.pinches) != 1 || g.pinches[0] != 2 {
+		t.Errorf("pinches %v, want a single one doubling the distance", g.pinches)
+	}
+	if len(g.pans) != 2 || g.pans[0] != [2]float32{5, 0} || g.pans[1] != [2]float32{10, 10} {
+		t.Errorf("pans %v, want [5 0] then [10 10]", g.pans)
+	}
+	if g.taps != 0 {
+		t.Errorf("%d taps during a pinch", g.taps)
+	}
+}
+
+func TestCancelTouches(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	g := listenGestures()
+	defer g.cancel()
+	im := NewInputManager()
+
+	im.touch(1, TouchBegin, 10, 10)
+	im.cancelTouches()
+	if phase := im.Touches[1].Phase; phase != TouchCancel {
+		t.Errorf("phase = %v once cancelled, want TouchCancel", phase)
+	}
+	touchFrame(im, 0)
+	if g.taps != 0 || len(im.Touches) != 0 {
+		t.Errorf("%d taps and %d touches left after cancelling, want none", g.taps, len(im.Touches))
+	}
+}
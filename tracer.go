@@ -0,0 +1,125 @@
+package minieng
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// MessageTrace describes a message dispatched by a MessageManager.
+type MessageTrace struct {
+	// Type is the Type() of the message
+	Type string
+	// Frame is the frame during which the message was sent, see Clock.Frame
+	Frame uint64
+	// Handlers is the number of handlers which were called
+	Handlers int
+	// Consumed is true when a handler stopped the propagation of the message
+	Consumed bool
+	// Posted is true when the message was queued with Post, and dispatched by Flush
+	Posted bool
+}
+
+// MessageTracer records the latest messages dispatched by the MessageManagers it has been set on, to help debugging
+// the messages which don't have the expected effect. To trace every MessageManager, including the ones of the
+// Scenes pushed later on:
+//
+//    minieng.SetMessageTracer(minieng.NewMessageTracer(1024))
+//
+// It only keeps a fixed number of traces, the oldest ones being overwritten.
+type MessageTracer struct {
+	lock   sync.Mutex
+	traces []MessageTrace
+	next   int
+	full   bool
+}
+
+// NewMessageTracer creates a MessageTracer keeping the given number of traces.
+func NewMessageTracer(size int) *MessageTracer {
+	if size < 1 {
+		size = 1
+	}
+	return &MessageTracer{traces: make([]MessageTrace, size)}
+}
+
+// trace adds a trace to the ring buffer, overwriting the oldest one when full.
+func (t *MessageTracer) trace(trace MessageTrace) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.traces[t.next] = trace
+	t.next++
+	if t.next == len(t.traces) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// Traces returns the recorded traces, from the oldest to the latest.
+func (t *MessageTracer) Traces() []MessageTrace {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.full {
+		return append([]MessageTrace(nil), t.traces[:t.next]...)
+	}
+	traces := make([]MessageTrace, 0, len(t.traces))
+	traces = append(traces, t.traces[t.next:]...)
+	return append(traces, t.traces[:t.next]...)
+}
+
+// LastFrames returns the recorded traces of the messages sent during the last n frames, including the current one,
+// from the oldest to the latest.
+func (t *MessageTracer) LastFrames(n int) []MessageTrace {
+	if n <= 0 {
+		return nil
+	}
+	frame := currentFrame()
+	var since uint64
+	if frame >= uint64(n) {
+		since = frame - uint64(n) + 1
+	}
+
+	// posted messages are traced when flushed, so the frames are not necessarily in order; the frames after the
+	// current one were recorded before the clock got reset
+	var traces []MessageTrace
+	for _, trace := range t.Traces() {
+		if trace.Frame >= since && trace.Frame <= frame {
+			traces = append(traces, trace)
+		}
+	}
+	return traces
+}
+
+// Clear forgets the recorded traces.
+func (t *MessageTracer) Clear() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.next = 0
+	t.full = false
+}
+
+// messageTracer holds the *MessageTracer set with SetMessageTracer
+var messageTracer atomic.Value
+
+// SetMessageTracer records every message dispatched by any MessageManager into the given MessageTracer, in
+// addition to the tracers set on each one with MessageManager.SetTracer. A nil tracer stops the recording.
+func SetMessageTracer(tracer *MessageTracer) {
+	messageTracer.Store(tracer)
+}
+
+// engineTracer returns the MessageTracer set with SetMessageTracer, if any.
+func engineTracer() *MessageTracer {
+	tracer, _ := messageTracer.Load().(*MessageTracer)
+	return tracer
+}
+
+// engineFrame is the frame number of the engine clock as of its last tick, the Time variable itself being only
+// safe to read from the main thread
+var engineFrame uint64
+
+// currentFrame returns the frame number of the engine clock, or 0 before it is started. It is safe to call from
+// any goroutine.
+func currentFrame() uint64 {
+	return atomic.LoadUint64(&engineFrame)
+}
@@ -0,0 +1,59 @@
+//+build !netgo,!android,!headless
+
+package minieng
+
+import (
+	"fmt"
+
+	"github.com/inkyblackness/imgui-go"
+)
+
+// DrawPanel draws an imgui window listing the messages recorded during the last n frames, the latest first. It
+// must be called from the Update of a System, between the imgui frame start and rendering done by the engine.
+func (t *MessageTracer) DrawPanel(n int) {
+	if !imgui.Begin("Messages") {
+		imgui.End()
+		return
+	}
+
+	if imgui.Button("Clear") {
+		t.Clear()
+	}
+	imgui.SameLine()
+	imgui.Text(fmt.Sprintf("frame %d", currentFrame()))
+	imgui.Separator()
+
+	imgui.BeginChild("traces")
+	imgui.Columns(4, "traces")
+	for _, title := range []string{"Frame", "Type", "Handlers", ""} {
+		imgui.Text(title)
+		imgui.NextColumn()
+	}
+	imgui.Separator()
+
+	traces := t.LastFrames(n)
+	for i := len(traces) - 1; i >= 0; i-- {
+		trace := traces[i]
+		imgui.Text(fmt.Sprintf("%d", trace.Frame))
+		imgui.NextColumn()
+		imgui.Text(trace.Type)
+		imgui.NextColumn()
+		imgui.Text(fmt.Sprintf("%d", trace.Handlers))
+		imgui.NextColumn()
+		switch {
+		case trace.Consumed && trace.Posted:
+			imgui.Text("posted, consumed")
+		case trace.Consumed:
+			imgui.Text("consumed")
+		case trace.Posted:
+			imgui.Text("posted")
+		default:
+			imgui.Text("")
+		}
+		imgui.NextColumn()
+	}
+	imgui.Columns(1, "")
+	imgui.EndChild()
+
+	imgui.End()
+}
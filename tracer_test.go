@@ -0,0 +1,94 @@
+//+build headless
+
+package minieng
+
+import (
+	"strings"
+	"testing"
+)
+
+// traceTypes joins the types of the traces
+func traceTypes(traces []MessageTrace) string {
+	types := make([]string, len(traces))
+	for i, trace := range traces {
+		types[i] = trace.Type
+	}
+	return strings.Join(types, ",")
+}
+
+type otherTestMessage struct{}
+
+func (otherTestMessage) Type() string { return "otherTestMessage" }
+
+func TestMessageTracerRing(t *testing.T) {
+	tracer := NewMessageTracer(2)
+	for _, trace := range []string{"a", "b", "c"} {
+		tracer.trace(MessageTrace{Type: trace})
+	}
+	if got := traceTypes(tracer.Traces()); got != "b,c" {
+		t.Errorf("Traces() = %s, want b,c", got)
+	}
+
+	tracer.Clear()
+	if traces := tracer.Traces(); len(traces) != 0 {
+		t.Errorf("Traces() = %v after Clear, want none", traces)
+	}
+}
+
+func TestMessageTracerDispatch(t *testing.T) {
+	defer func(clock *Clock) { Time = clock }(Time)
+	Time = NewManualClock()
+	Time.Tick()
+
+	mm := &MessageManager{}
+	tracer := NewMessageTracer(8)
+	mm.SetTracer(tracer)
+	SubscribeWithPriority(mm, 0, func(testMessage) bool { return true })
+	Subscribe(mm, func(testMessage) {})
+
+	mm.Dispatch(testMessage{})
+	mm.Post(otherTestMessage{})
+	Time.Tick()
+	mm.Flush()
+
+	traces := tracer.Traces()
+	if len(traces) != 2 {
+		t.Fatalf("traced %v, want 2 messages", traces)
+	}
+	if got, want := traces[0], (MessageTrace{Type: "testMessage", Frame: 1, Handlers: 1, Consumed: true}); got != want {
+		t.Errorf("dispatched message traced as %+v, want %+v", got, want)
+	}
+	if got, want := traces[1], (MessageTrace{Type: "otherTestMessage", Frame: 1, Posted: true}); got != want {
+		t.Errorf("posted message traced as %+v, want %+v", got, want)
+	}
+
+	mm.Dispatch(otherTestMessage{})
+	if got := traceTypes(tracer.LastFrames(1)); got != "otherTestMessage" {
+		t.Errorf("LastFrames(1) = %s, want the message of the current frame", got)
+	}
+	if got := len(tracer.LastFrames(2)); got != 3 {
+		t.Errorf("LastFrames(2) has %d traces, want 3", got)
+	}
+}
+
+func TestSetMessageTracer(t *testing.T) {
+	defer SetMessageTracer(nil)
+
+	tracer := NewMessageTracer(8)
+	SetMessageTracer(tracer)
+	first, second := &MessageManager{}, &MessageManager{}
+	// a MessageManager tracing into the same tracer records its messages once
+	second.SetTracer(tracer)
+
+	first.Dispatch(testMessage{})
+	second.Dispatch(otherTestMessage{})
+	if got := traceTypes(tracer.Traces()); got != "testMessage,otherTestMessage" {
+		t.Errorf("Traces() = %s, want both messages once", got)
+	}
+
+	SetMessageTracer(nil)
+	first.Dispatch(testMessage{})
+	if got := len(tracer.Traces()); got != 2 {
+		t.Errorf("%d traces after SetMessageTracer(nil), want 2", got)
+	}
+}
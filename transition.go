@@ -0,0 +1,282 @@
+package minieng
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Transition describes how TransitionTo switches to a Scene.
+type Transition struct {
+	// Loading is an optional Scene shown while the new Scene is preloaded. Its Systems can display the
+	// `LoadingProgress`.
+	Loading Scene
+
+	// FadeDuration is the duration, in seconds, of the cross-fade from the previous Scene (or the Loading one)
+	// to the new Scene. There is no fade when 0, nor when the new Scene is already in the stack of Scenes.
+	FadeDuration float32
+
+	// ForceNewWorld creates a new World for the Scene, as with SetScene.
+	ForceNewWorld bool
+}
+
+// transition is the state of the current call to TransitionTo.
+type transition struct {
+	Transition
+
+	scene Scene
+
+	// preload runs the Preload of the Scene, nil when it is already set up
+	preload *preload
+
+	// fading is true once the new Scene is pushed over the previous one
+	fading  bool
+	elapsed float32
+}
+
+// preload runs the Preload of a Scene on a background goroutine, the files it loads through Files being uploaded
+// on the main thread. Only the main thread touches owned and finished.
+type preload struct {
+	// goroutine identifies the goroutine running Preload
+	goroutine uint64
+
+	// total counts the urls given to Files by Preload so far, loaded the ones done, successfully or not
+	total, loaded int32
+
+	// owned are the urls loaded successfully, to be owned by the Scene once it is set up
+	owned    map[string]bool
+	finished bool
+}
+
+var (
+	currentTransition *transition
+
+	// mainGoroutine identifies the goroutine running the main loop, 0 until RunPreparation is called
+	mainGoroutine uint64
+
+	// mainQueue holds the functions given to postMain, to run during the next frame
+	mainQueue []func()
+	mainLock  sync.Mutex
+
+	// preloads are the Preloads running in the background, by goroutine
+	preloads    = make(map[uint64]*preload)
+	preloadLock sync.Mutex
+)
+
+// TransitionTo switches to the given Scene like SetScene does, except its Preload is called on a background
+// goroutine, so the window keeps responding. Meanwhile, the current Scene (or the Loading Scene of the Transition)
+// keeps running. The files Preload loads through Files are read and decoded in the background (see Decoder), and
+// uploaded on the main thread; Preload must not do anything else requiring the main thread. Once Preload returns,
+// the Scene is set up on the main thread, and replaces the previous one, with an optional cross-fade.
+func TransitionTo(s Scene, t Transition) {
+	if t.Loading != nil {
+		SetScene(t.Loading, false)
+	}
+
+	RegisterScene(s)
+	wrapper := scenes[s.Type()]
+
+	current := &transition{Transition: t, scene: s}
+	currentTransition = current
+
+	if wrapper.world != nil && !t.ForceNewWorld {
+		// already set up, nothing to preload
+		return
+	}
+	if wrapper.world != nil && !inStack(wrapper) {
+		// release what the previous World loaded, before loading again; a Scene in use is only discarded once
+		// it is replaced
+		if err := discard(wrapper); err != nil {
+			log.Println("[WARNING] unable to discard scene:", err)
+		}
+	}
+
+	p := &preload{owned: make(map[string]bool)}
+	current.preload = p
+	go p.run(current)
+}
+
+// LoadingProgress returns the progress, between 0 and 1, of the files loaded through Files by the Preload of the
+// Scene given to TransitionTo. It only knows about the urls Preload asked for so far.
+func LoadingProgress() float32 {
+	if currentTransition == nil || currentTransition.preload == nil || currentTransition.preload.finished {
+		return 1
+	}
+	p := currentTransition.preload
+	total := atomic.LoadInt32(&p.total)
+	if total == 0 {
+		return 0
+	}
+	return float32(atomic.LoadInt32(&p.loaded)) / float32(total)
+}
+
+// FadeProgress returns the progress, between 0 and 1, of the cross-fade started by TransitionTo; 1 when there is
+// none. Renderers of the new Scene can use it as their opacity, as the previous Scene is rendered below it.
+func FadeProgress() float32 {
+	if currentTransition == nil || !currentTransition.fading {
+		return 1
+	}
+	return currentTransition.elapsed / currentTransition.FadeDuration
+}
+
+// run calls the Preload of the Scene on the calling goroutine. A panic in Preload is raised again on the main
+// thread.
+func (p *preload) run(t *transition) {
+	p.goroutine = goroutineID()
+	preloadLock.Lock()
+	preloads[p.goroutine] = p
+	preloadLock.Unlock()
+
+	defer func() {
+		preloadLock.Lock()
+		delete(preloads, p.goroutine)
+		preloadLock.Unlock()
+
+		r := recover()
+		postMain(func() {
+			p.finished = true
+			if currentTransition != t {
+				// the transition was abandoned, nobody is going to own the files
+				releaseURLs(p.owned)
+			}
+			if r != nil {
+				panic(r)
+			}
+		})
+	}()
+
+	t.scene.Preload()
+}
+
+// preloadOn returns the Preload running on the given goroutine, if any.
+func preloadOn(goroutine uint64) *preload {
+	preloadLock.Lock()
+	defer preloadLock.Unlock()
+	return preloads[goroutine]
+}
+
+// own records that the url was loaded for the Scene. It is called on the main thread.
+func (p *preload) own(url string) {
+	ownerLock.Lock()
+	defer ownerLock.Unlock()
+
+	if !p.owned[url] {
+		p.owned[url] = true
+		owners[url]++
+	}
+}
+
+// postMain queues fn to be run on the main thread during the next frame, without waiting for it. It is safe to
+// call from any goroutine.
+func postMain(fn func()) {
+	mainLock.Lock()
+	mainQueue = append(mainQueue, fn)
+	mainLock.Unlock()
+	wakeMain()
+}
+
+// markMainThread records the calling goroutine as the one running the main loop.
+func markMainThread() {
+	atomic.StoreUint64(&mainGoroutine, goroutineID())
+}
+
+// onMainThread returns whether the calling goroutine runs the main loop. It is true as well before the main loop
+// starts, and where goroutines can't be told apart.
+func onMainThread() bool {
+	main := atomic.LoadUint64(&mainGoroutine)
+	if main == 0 {
+		return true
+	}
+	id := goroutineID()
+	return id == 0 || id == main
+}
+
+// goroutineID returns the id of the calling goroutine, read from its stack trace, or 0 when unknown.
+func goroutineID() uint64 {
+	var buf [64]byte
+	// the trace starts with "goroutine 42 ["
+	fields := bytes.Fields(buf[:runtime.Stack(buf[:], false)])
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(fields[1]), 10, 64)
+	return id
+}
+
+// updateTransition runs the functions queued by postMain, and moves the current transition forward.
+func updateTransition(dt float32) {
+	mainLock.Lock()
+	queued := mainQueue
+	mainQueue = nil
+	mainLock.Unlock()
+	for _, fn := range queued {
+		fn()
+	}
+
+	t := currentTransition
+	if t == nil {
+		return
+	}
+
+	if t.fading {
+		t.elapsed += dt
+		if t.elapsed >= t.FadeDuration {
+			// drop the previous Scenes, they were hidden when the new one was pushed
+			sceneStack = sceneStack[len(sceneStack)-1:]
+			currentTransition = nil
+			evictHidden()
+		}
+		return
+	}
+
+	if t.preload != nil && !t.preload.finished {
+		return
+	}
+
+	wrapper := scenes[t.scene.Type()]
+	if t.FadeDuration <= 0 || inStack(wrapper) {
+		for i := len(sceneStack) - 1; i >= 0; i-- {
+			if hider, ok := sceneStack[i].wrapper.scene.(Hider); ok {
+				hider.Hide()
+			}
+		}
+		sceneStack = nil
+		pushScene(t.scene, t.ForceNewWorld, t.preload != nil, Overlay{})
+		t.adopt(wrapper)
+		currentTransition = nil
+		evictHidden()
+		return
+	}
+
+	if hider, ok := currentScene.(Hider); ok {
+		hider.Hide()
+	}
+	pushScene(t.scene, t.ForceNewWorld, t.preload != nil, Overlay{RenderBelow: true})
+	t.adopt(wrapper)
+	t.fading = true
+}
+
+// adopt makes the Scene, once set up, the owner of the files loaded by its Preload in the background.
+func (t *transition) adopt(wrapper *sceneWrapper) {
+	if t.preload == nil {
+		return
+	}
+
+	ownerLock.Lock()
+	defer ownerLock.Unlock()
+
+	if wrapper.urls == nil {
+		wrapper.urls = make(map[string]bool)
+	}
+	for url := range t.preload.owned {
+		if wrapper.urls[url] {
+			// the Scene loaded it again during Preload or Setup
+			owners[url]--
+			continue
+		}
+		wrapper.urls[url] = true
+	}
+}
@@ -0,0 +1,192 @@
+//+build headless
+
+package minieng
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// decodeTestLoader records where its files are decoded and uploaded, blocking the decoding of block until it is
+// closed
+type decodeTestLoader struct {
+	block           string
+	release         chan struct{}
+	decodedOnMain   bool
+	uploadedOffMain bool
+	loaded          map[string]bool
+}
+
+func (l *decodeTestLoader) Load(url string, data io.Reader) error {
+	l.loaded[url] = true
+	return nil
+}
+
+func (l *decodeTestLoader) Decode(url string, data io.Reader) (interface{}, error) {
+	if onMainThread() {
+		l.decodedOnMain = true
+	}
+	if url == l.block {
+		<-l.release
+	}
+	return ioutil.ReadAll(data)
+}
+
+func (l *decodeTestLoader) Upload(url string, decoded interface{}) error {
+	if !onMainThread() {
+		l.uploadedOffMain = true
+	}
+	l.loaded[url] = true
+	return nil
+}
+
+func (l *decodeTestLoader) Unload(url string) error {
+	delete(l.loaded, url)
+	return nil
+}
+
+func (l *decodeTestLoader) Resource(url string) (Resource, error) { return nil, nil }
+
+// preloadTestScene loads its urls in two calls to Files.Load
+type preloadTestScene struct {
+	testScene
+	preloads, setups int
+	err              error
+}
+
+func (s *preloadTestScene) Preload() {
+	s.preloads++
+	if s.err = Files.Load("a.tst"); s.err == nil {
+		s.err = Files.Load("b.tst")
+	}
+}
+
+func (s *preloadTestScene) Setup(w *World) { s.setups++ }
+
+// withTestFiles registers the loader for the .tst files, found in a temporary root
+func withTestFiles(t *testing.T, loader FileLoader, urls ...string) func() {
+	root, err := ioutil.TempDir("", "minieng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range urls {
+		if err := ioutil.WriteFile(filepath.Join(root, url), []byte(url), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previousRoot := Files.root
+	Files.SetRoot(root)
+	Files.Register(".tst", loader)
+
+	return func() {
+		Files.SetRoot(previousRoot)
+		delete(Files.formats, ".tst")
+		os.RemoveAll(root)
+	}
+}
+
+// iterateUntil runs frames until done returns true, failing after a second
+func iterateUntil(t *testing.T, done func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		RunIteration()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTransitionPreload(t *testing.T) {
+	defer resetScenes()
+	defer atomic.StoreUint64(&mainGoroutine, 0)
+	loader := &decodeTestLoader{block: "b.tst", release: make(chan struct{}), loaded: make(map[string]bool)}
+	defer withTestFiles(t, loader, "a.tst", "b.tst")()
+
+	RunPreparation(&testScene{name: "loading"})
+	scene := &preloadTestScene{testScene: testScene{name: "preloaded"}}
+	TransitionTo(scene, Transition{})
+
+	// a.tst is loaded, b.tst is being decoded
+	iterateUntil(t, func() bool { return LoadingProgress() == 0.5 })
+	if CurrentScene() == scene {
+		t.Error("the Scene was set up before its Preload returned")
+	}
+	close(loader.release)
+
+	iterateUntil(t, func() bool { return CurrentScene() == scene })
+	if scene.err != nil {
+		t.Errorf("Files.Load: %v", scene.err)
+	}
+	if scene.preloads != 1 || scene.setups != 1 {
+		t.Errorf("Preload called %d times and Setup %d times, want once each", scene.preloads, scene.setups)
+	}
+	if loader.decodedOnMain || loader.uploadedOffMain {
+		t.Errorf("decoded on the main thread: %v, uploaded away from it: %v, want neither", loader.decodedOnMain, loader.uploadedOffMain)
+	}
+	if p := LoadingProgress(); p != 1 {
+		t.Errorf("LoadingProgress() = %v once done, want 1", p)
+	}
+	if urls := scenes["preloaded"].urls; !urls["a.tst"] || !urls["b.tst"] {
+		t.Errorf("the Scene owns %v, want both files", urls)
+	}
+}
+
+func TestPostMain(t *testing.T) {
+	defer atomic.StoreUint64(&mainGoroutine, 0)
+	markMainThread()
+
+	var ran []int
+	done := make(chan struct{})
+	go func() {
+		// more functions than a frame used to hold, none of them blocks
+		for i := 0; i < 100; i++ {
+			i := i
+			postMain(func() { ran = append(ran, i) })
+		}
+		close(done)
+	}()
+	<-done
+
+	updateTransition(0)
+	if len(ran) != 100 {
+		t.Fatalf("ran %d functions, want 100", len(ran))
+	}
+	for i, n := range ran {
+		if n != i {
+			t.Fatalf("ran the functions in the order %v", ran)
+		}
+	}
+}
+
+func TestTransitionFade(t *testing.T) {
+	defer resetScenes()
+	defer atomic.StoreUint64(&mainGoroutine, 0)
+
+	next := &testScene{name: "next"}
+	RegisterScene(next)
+	RunPreparation(next)
+	SetScene(&testScene{name: "previous"}, false)
+
+	TransitionTo(next, Transition{FadeDuration: 1})
+	Time.Advance(500 * time.Millisecond)
+	RunIteration()
+	if CurrentScene() != next || len(sceneStack) != 2 {
+		t.Fatalf("the Scene was not pushed over the previous one")
+	}
+	Time.Advance(500 * time.Millisecond)
+	RunIteration()
+	if p := FadeProgress(); p != 0.5 {
+		t.Errorf("FadeProgress() = %v, want 0.5", p)
+	}
+	Time.Advance(time.Second)
+	RunIteration()
+	if len(sceneStack) != 1 || FadeProgress() != 1 {
+		t.Errorf("%d Scenes in the stack after the fade, want 1", len(sceneStack))
+	}
+}
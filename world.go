@@ -1,23 +1,214 @@
 package minieng
 
 import (
-	"sort"
+	"fmt"
+	"reflect"
+	"sync"
 )
 
 // World contains a bunch of Entities, and a bunch of Systems. It is the
 // recommended way to run ecs.
 type World struct {
-	systems systems
+	// registered holds the Systems in insertion order, systems in execution order
+	registered []*systemEntry
+	systems    systems
+	batches    [][]*systemEntry
+	components components
+
+	// mailbox receives the EntityAddedMessage and EntityRemovedMessage of this World
+	mailbox *MessageManager
+
+	// updating is true while the Systems are being updated; entity operations
+	// are then queued in pending until the end of the frame
+	updating    bool
+	pending     []entityOperation
+	pendingLock sync.Mutex
+}
+
+// pass selects which Systems are run by an update.
+type pass int
+
+const (
+	// allSystems runs every System
+	allSystems pass = iota
+	// updatePass only runs the Systems which are not a Renderer
+	updatePass
+	// renderPass only runs the Renderer Systems
+	renderPass
+)
+
+// systemEntry is a System added to the World.
+type systemEntry struct {
+	system System
+	paused bool
+	// removed is set once the System got removed, the batches being run may still hold it
+	removed bool
+}
+
+// entityOperation is an entity addition or removal queued during an update.
+type entityOperation struct {
+	entity BasicEntity
+	add    func()
+	remove bool
 }
 
-// AddSystem adds the given System to the World, sorted by priority.
-func (w *World) AddSystem(system System) {
+// AddSystem adds the given System to the World, sorted by priority. Systems
+// with the same priority run in the order they were added, unless they declare
+// dependencies with RunsAfter or RunsBefore. If those dependencies are cyclic,
+// the System is not added and an error is returned; when the cycle comes from
+// Systems added by its New method, it is deinitialized first.
+func (w *World) AddSystem(system System) error {
+	// check the dependencies before initializing anything
+	if _, err := sortSystems(append(w.registeredSystems(), system)); err != nil {
+		return err
+	}
+
 	if initializer, ok := system.(Initializer); ok {
 		initializer.New(w)
 	}
 
-	w.systems = append(w.systems, system)
-	sort.Sort(w.systems)
+	// New may have added other Systems, sort again
+	w.registered = append(w.registered[:len(w.registered):len(w.registered)], &systemEntry{system: system})
+	if err := w.reschedule(); err != nil {
+		// the Systems added by New conflict with this one, which is left out;
+		// w.systems still lists the others
+		w.registered = w.registered[:len(w.registered)-1]
+		if deinitializer, ok := system.(Deinitializer); ok {
+			deinitializer.Deinit(w)
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveSystem removes the given System from the World, calling its Deinit
+// method if it implements Deinitializer. It is safe to call during an update:
+// the System is not updated anymore, even later during the same frame.
+func (w *World) RemoveSystem(system System) {
+	i := w.indexOf(system)
+	if i < 0 {
+		return
+	}
+	w.registered[i].removed = true
+
+	// don't modify the slices in place, an update may be iterating over them
+	registered := make([]*systemEntry, 0, len(w.registered)-1)
+	registered = append(registered, w.registered[:i]...)
+	w.registered = append(registered, w.registered[i+1:]...)
+	// removing a System can't make the dependencies cyclic
+	w.reschedule()
+
+	if deinitializer, ok := system.(Deinitializer); ok {
+		deinitializer.Deinit(w)
+	}
+}
+
+// registeredSystems returns the Systems in insertion order.
+func (w *World) registeredSystems() systems {
+	registered := make(systems, len(w.registered))
+	for i, entry := range w.registered {
+		registered[i] = entry.system
+	}
+	return registered
+}
+
+// reschedule sorts the registered Systems again, into new slices.
+func (w *World) reschedule() error {
+	order, err := sortSystems(w.registeredSystems())
+	if err != nil {
+		return err
+	}
+
+	sorted := make(systems, len(order))
+	entries := make([]*systemEntry, len(order))
+	for i, j := range order {
+		sorted[i] = w.registered[j].system
+		entries[i] = w.registered[j]
+	}
+	w.systems = sorted
+	w.batches = schedule(entries)
+	return nil
+}
+
+// indexOf returns the index in w.registered of the given System, or -1. The
+// Systems are compared by identity, as they may not be comparable.
+func (w *World) indexOf(system System) int {
+	for i, entry := range w.registered {
+		if sameSystem(entry.system, system) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameSystem returns whether a and b are the same System, without comparing
+// values which can't be compared with ==. The Systems which are neither
+// comparable nor a slice, a map or a func are never the same.
+func sameSystem(a, b System) bool {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if t.Comparable() {
+		return a == b
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch t.Kind() {
+	case reflect.Slice:
+		return va.Pointer() == vb.Pointer() && va.Len() == vb.Len()
+	case reflect.Map, reflect.Func:
+		return va.Pointer() == vb.Pointer()
+	}
+	return false
+}
+
+// System looks up the first System which has the type pointed to by ptr, and
+// stores it in ptr. It returns false if the World has no such System:
+//
+//    var render *common.RenderSystem
+//    if w.System(&render) {
+//        w.RemoveSystem(render)
+//    }
+func (w *World) System(ptr interface{}) bool {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Sprintf("System needs a non-nil pointer, got %T", ptr))
+	}
+
+	t := v.Type().Elem()
+	for _, system := range w.systems {
+		if reflect.TypeOf(system) == t {
+			v.Elem().Set(reflect.ValueOf(system))
+			return true
+		}
+	}
+	return false
+}
+
+// PauseSystem stops updating the given System, without removing it from the
+// World: it still gets its entities added and removed. The other Systems of
+// the same type keep running. It must not be called from a Concurrent System.
+func (w *World) PauseSystem(system System) {
+	w.setPaused(system, true)
+}
+
+// ResumeSystem updates the given System again after a call to PauseSystem.
+func (w *World) ResumeSystem(system System) {
+	w.setPaused(system, false)
+}
+
+// setPaused pauses or resumes the given System, if it is in the World.
+func (w *World) setPaused(system System, paused bool) {
+	if i := w.indexOf(system); i >= 0 {
+		w.registered[i].paused = paused
+	}
+}
+
+// Paused returns whether the given System has been paused.
+func (w *World) Paused(system System) bool {
+	i := w.indexOf(system)
+	return i >= 0 && w.registered[i].paused
 }
 
 // Systems returns the list of Systems managed by the World.
@@ -27,15 +218,110 @@ func (w *World) Systems() []System {
 
 // Update updates each System managed by the World. It is invoked by the engine
 // once every frame, with dt being the duration since the previous update.
+//
+// Entities added or removed during the update are only added or removed once
+// all Systems have been updated. This only holds for AddEntity and
+// RemoveEntity: calling the Add method of a System directly adds the entity
+// right away.
 func (w *World) Update(dt float32) {
-	for _, system := range w.Systems() {
+	w.update(dt, allSystems)
+}
+
+// update updates the Systems selected by p, then applies the queued entity
+// operations.
+func (w *World) update(dt float32, p pass) {
+	w.updateSystems(dt, p)
+	w.flush()
+}
+
+// updateSystems updates the Systems selected by p. The entity operations are
+// queued meanwhile, even if a System panics.
+func (w *World) updateSystems(dt float32, p pass) {
+	w.updating = true
+	defer func() { w.updating = false }()
+
+	w.run(func(system System) {
+		if p != allSystems {
+			if _, renderer := system.(Renderer); renderer != (p == renderPass) {
+				return
+			}
+		}
 		system.Update(dt)
+	})
+}
+
+// FixedUpdate updates each System implementing FixedUpdater, in the same
+// order as Update.
+func (w *World) FixedUpdate(dt float32) {
+	updating := w.updating
+	w.updating = true
+	defer func() { w.updating = updating }()
+
+	w.run(func(system System) {
+		if fixed, ok := system.(FixedUpdater); ok {
+			fixed.FixedUpdate(dt)
+		}
+	})
+}
+
+// AddEntity adds the entity to the World. add, which may be nil, is where the
+// entity gets added to its Systems and gets its components, i.e.:
+//
+//    w.AddEntity(basic, func() {
+//        renderSystem.Add(&basic, &render)
+//    })
+//
+// When called during an update, add is only invoked at the end of the frame,
+// which is why Systems should not be given entities directly meanwhile.
+// An EntityAddedMessage is dispatched once the entity has been added.
+func (w *World) AddEntity(e BasicEntity, add func()) {
+	if w.updating {
+		w.pendingLock.Lock()
+		w.pending = append(w.pending, entityOperation{entity: e, add: add})
+		w.pendingLock.Unlock()
+		return
+	}
+
+	if add != nil {
+		add()
+	}
+	if w.mailbox != nil {
+		w.mailbox.Dispatch(EntityAddedMessage{Entity: e})
 	}
 }
 
-// RemoveEntity removes the entity across all systems.
+// RemoveEntity removes the entity across all systems, and detaches all of its
+// components. When called during an update, the removal only happens at the
+// end of the frame. An EntityRemovedMessage is dispatched once the entity has
+// been removed.
 func (w *World) RemoveEntity(e BasicEntity) {
+	if w.updating {
+		w.pendingLock.Lock()
+		w.pending = append(w.pending, entityOperation{entity: e, remove: true})
+		w.pendingLock.Unlock()
+		return
+	}
+
 	for _, sys := range w.systems {
 		sys.Remove(e)
 	}
+	w.components.removeAll(e)
+
+	if w.mailbox != nil {
+		w.mailbox.Dispatch(EntityRemovedMessage{Entity: e})
+	}
+}
+
+// flush applies the entity operations queued during the update, in order.
+func (w *World) flush() {
+	pending := w.pending
+	w.pending = nil
+
+	for _, op := range pending {
+		if op.remove {
+			w.RemoveEntity(op.entity)
+		} else {
+			w.AddEntity(op.entity, op.add)
+		}
+	}
 }
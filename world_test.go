@@ -0,0 +1,320 @@
+//+build headless
+
+package minieng
+
+import (
+	"strings"
+	"testing"
+)
+
+// logSystem logs its updates into log, it is embedded by the test Systems so that they have distinct types
+type logSystem struct {
+	name string
+	log  *[]string
+}
+
+func (s *logSystem) Update(float32)     { *s.log = append(*s.log, s.name) }
+func (s *logSystem) Remove(BasicEntity) {}
+
+type inputTestSystem struct{ logSystem }
+
+type physicsTestSystem struct{ logSystem }
+
+func (*physicsTestSystem) Priority() int       { return 10 }
+func (*physicsTestSystem) RunsAfter() []System { return []System{(*inputTestSystem)(nil)} }
+
+type cameraTestSystem struct{ logSystem }
+
+func (*cameraTestSystem) Priority() int        { return 5 }
+func (*cameraTestSystem) RunsBefore() []System { return []System{(*inputTestSystem)(nil)} }
+
+func TestSystemDependencies(t *testing.T) {
+	var log []string
+	w := &World{}
+	for _, system := range []System{
+		&physicsTestSystem{logSystem{"physics", &log}},
+		&inputTestSystem{logSystem{"input", &log}},
+		&cameraTestSystem{logSystem{"camera", &log}},
+	} {
+		if err := w.AddSystem(system); err != nil {
+			t.Fatalf("AddSystem: %v", err)
+		}
+	}
+
+	w.Update(1)
+	if got, want := strings.Join(log, ","), "camera,input,physics"; got != want {
+		t.Errorf("update order = %s, want %s", got, want)
+	}
+}
+
+type pingTestSystem struct{ logSystem }
+
+func (*pingTestSystem) RunsAfter() []System { return []System{(*pongTestSystem)(nil)} }
+
+type pongTestSystem struct {
+	logSystem
+	added  *pingTestSystem
+	deinit bool
+}
+
+func (*pongTestSystem) RunsAfter() []System { return []System{(*pingTestSystem)(nil)} }
+
+func (s *pongTestSystem) New(w *World) {
+	if s.added != nil {
+		w.AddSystem(s.added)
+	}
+}
+
+func (s *pongTestSystem) Deinit(w *World) {
+	s.deinit = true
+	if s.added != nil {
+		w.RemoveSystem(s.added)
+	}
+}
+
+func TestSystemDependencyCycle(t *testing.T) {
+	var log []string
+	w := &World{}
+	if err := w.AddSystem(&pingTestSystem{logSystem{"ping", &log}}); err != nil {
+		t.Fatalf("AddSystem: %v", err)
+	}
+
+	err := w.AddSystem(&pongTestSystem{logSystem: logSystem{"pong", &log}})
+	if err == nil || !strings.Contains(err.Error(), "cyclic") {
+		t.Fatalf("AddSystem error = %v, want a cycle", err)
+	}
+	if n := len(w.Systems()); n != 1 {
+		t.Errorf("%d Systems after the cycle, want 1", n)
+	}
+}
+
+func TestSystemDependencyCycleFromNew(t *testing.T) {
+	var log []string
+	w := &World{}
+	pong := &pongTestSystem{logSystem: logSystem{"pong", &log}, added: &pingTestSystem{logSystem{"ping", &log}}}
+
+	if err := w.AddSystem(pong); err == nil {
+		t.Fatal("AddSystem succeeded, want a cycle")
+	}
+	if !pong.deinit {
+		t.Error("the System was not deinitialized")
+	}
+	if n := len(w.Systems()); n != 0 {
+		t.Errorf("%d Systems after the cycle, want 0", n)
+	}
+
+	w.Update(1)
+	if len(log) != 0 {
+		t.Errorf("updated %v, want nothing", log)
+	}
+}
+
+// entityTestSystem tracks its entities, removing one during its Update when asked to
+type entityTestSystem struct {
+	world    *World
+	entities map[uint64]bool
+	remove   *BasicEntity
+	seen     int
+	panics   bool
+}
+
+func (s *entityTestSystem) add(e BasicEntity) { s.entities[e.ID()] = true }
+
+func (s *entityTestSystem) Remove(e BasicEntity) { delete(s.entities, e.ID()) }
+
+func (s *entityTestSystem) Update(float32) {
+	if s.panics {
+		panic("update failed")
+	}
+	if s.remove != nil {
+		s.world.RemoveEntity(*s.remove)
+		added := NewBasic()
+		s.world.AddEntity(added, func() { s.add(added) })
+	}
+	s.seen = len(s.entities)
+}
+
+func TestEntityOperationsDeferred(t *testing.T) {
+	w := &World{}
+	s := &entityTestSystem{world: w, entities: make(map[uint64]bool)}
+	if err := w.AddSystem(s); err != nil {
+		t.Fatalf("AddSystem: %v", err)
+	}
+
+	e := NewBasic()
+	w.AddEntity(e, func() { s.add(e) })
+	if !s.entities[e.ID()] {
+		t.Fatal("the entity was not added outside of an update")
+	}
+
+	s.remove = &e
+	w.Update(1)
+	if s.seen != 1 {
+		t.Errorf("the System saw %d entities during the update, want 1", s.seen)
+	}
+	if s.entities[e.ID()] || len(s.entities) != 1 {
+		t.Errorf("entities after the update = %v, want only the added one", s.entities)
+	}
+}
+
+func TestEntityOperationsAfterPanic(t *testing.T) {
+	w := &World{}
+	s := &entityTestSystem{world: w, entities: make(map[uint64]bool), panics: true}
+	if err := w.AddSystem(s); err != nil {
+		t.Fatalf("AddSystem: %v", err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		w.Update(1)
+	}()
+
+	e := NewBasic()
+	w.AddEntity(e, func() { s.add(e) })
+	if !s.entities[e.ID()] {
+		t.Error("the entity was queued after an update which panicked")
+	}
+}
+
+func TestEntityMessages(t *testing.T) {
+	w := &World{mailbox: &MessageManager{}}
+	s := &entityTestSystem{world: w, entities: make(map[uint64]bool)}
+	if err := w.AddSystem(s); err != nil {
+		t.Fatalf("AddSystem: %v", err)
+	}
+
+	var added, removed []uint64
+	Subscribe(w.mailbox, func(msg EntityAddedMessage) { added = append(added, msg.Entity.ID()) })
+	Subscribe(w.mailbox, func(msg EntityRemovedMessage) { removed = append(removed, msg.Entity.ID()) })
+
+	e := NewBasic()
+	w.AddEntity(e, func() { s.add(e) })
+	s.remove = &e
+	w.Update(1)
+
+	if len(added) != 2 || added[0] != e.ID() {
+		t.Errorf("EntityAddedMessage for %v, want %d and the entity added during the update", added, e.ID())
+	}
+	if len(removed) != 1 || removed[0] != e.ID() {
+		t.Errorf("EntityRemovedMessage for %v, want %d", removed, e.ID())
+	}
+}
+
+// countSystem counts its updates
+type countSystem struct{ updates int }
+
+func (s *countSystem) Remove(BasicEntity) {}
+
+func (s *countSystem) Update(float32) { s.updates++ }
+
+// sliceTestSystem is a System which can't be compared with ==
+type sliceTestSystem []int
+
+func (s sliceTestSystem) Remove(BasicEntity) {}
+
+func (s sliceTestSystem) Update(float32) { s[0]++ }
+
+// removerSystem removes another System during its Update
+type removerSystem struct {
+	world  *World
+	remove System
+}
+
+func (s *removerSystem) Remove(BasicEntity) {}
+
+func (s *removerSystem) Update(float32) { s.world.RemoveSystem(s.remove) }
+
+func TestPauseSystemInstance(t *testing.T) {
+	w := &World{}
+	a, b := &countSystem{}, &countSystem{}
+	w.AddSystem(a)
+	w.AddSystem(b)
+
+	w.PauseSystem(a)
+	w.Update(1)
+	if a.updates != 0 || b.updates != 1 {
+		t.Errorf("updates = %d, %d, want 0, 1", a.updates, b.updates)
+	}
+	if !w.Paused(a) || w.Paused(b) {
+		t.Errorf("Paused = %v, %v, want true, false", w.Paused(a), w.Paused(b))
+	}
+
+	w.ResumeSystem(a)
+	w.Update(1)
+	if a.updates != 1 {
+		t.Errorf("updates after ResumeSystem = %d, want 1", a.updates)
+	}
+}
+
+func TestNonComparableSystem(t *testing.T) {
+	w := &World{}
+	s, other := sliceTestSystem{0}, sliceTestSystem{0}
+	w.AddSystem(s)
+	w.AddSystem(other)
+
+	w.PauseSystem(s)
+	w.Update(1)
+	if s[0] != 0 || other[0] != 1 {
+		t.Errorf("updates = %d, %d, want 0, 1", s[0], other[0])
+	}
+
+	w.RemoveSystem(s)
+	if len(w.Systems()) != 1 || !sameSystem(w.Systems()[0], other) {
+		t.Errorf("Systems() = %v, want only the other System", w.Systems())
+	}
+}
+
+func TestSystemRemovedDuringUpdate(t *testing.T) {
+	w := &World{}
+	removed := &countSystem{}
+	w.AddSystem(&removerSystem{world: w, remove: removed})
+	w.AddSystem(removed)
+
+	w.Update(1)
+	if removed.updates != 0 {
+		t.Errorf("the removed System got %d updates, want 0", removed.updates)
+	}
+	if len(w.Systems()) != 1 {
+		t.Errorf("%d Systems left, want 1", len(w.Systems()))
+	}
+}
+
+// concurrentTestSystem runs concurrently, panicking during its Update when asked to
+type concurrentTestSystem struct {
+	panics  bool
+	updated bool
+}
+
+func (s *concurrentTestSystem) Remove(BasicEntity) {}
+
+func (s *concurrentTestSystem) Update(float32) {
+	if s.panics {
+		panic("concurrent update failed")
+	}
+	s.updated = true
+}
+
+func (s *concurrentTestSystem) Reads() []interface{}  { return nil }
+func (s *concurrentTestSystem) Writes() []interface{} { return nil }
+
+func TestConcurrentSystemPanic(t *testing.T) {
+	w := &World{}
+	failing, other := &concurrentTestSystem{panics: true}, &concurrentTestSystem{}
+	w.AddSystem(failing)
+	w.AddSystem(other)
+	if len(w.batches) != 1 {
+		t.Fatalf("%d batches, want the two Systems running together", len(w.batches))
+	}
+
+	var recovered interface{}
+	func() {
+		defer func() { recovered = recover() }()
+		w.Update(1)
+	}()
+	if recovered != "concurrent update failed" {
+		t.Errorf("recovered %v, want the panic of the System", recovered)
+	}
+	if !other.updated {
+		t.Error("the other System of the batch was not updated")
+	}
+}